@@ -0,0 +1,115 @@
+package ycache
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/azeroth-sha/y/yconv"
+)
+
+var ErrNotInteger = errors.New("ycache: value is not an integer")
+
+func (c *cache[K, V]) Incr(key K, delta int64, opts ...ItemOption) (val int64, err error) {
+	if c.isClosed() {
+		return 0, ErrClosed
+	}
+	c.warm(key)
+	s := c.getShard(key)
+	nv, exp, ok := s.Update(c.opts, key, func(old V, has bool) (V, bool) {
+		var cur int64
+		if has {
+			if cur, err = yconv.Digit[int64](any(old)); err != nil {
+				err = fmt.Errorf("%w: %v", ErrNotInteger, err)
+				return old, false
+			}
+		}
+		val = cur + delta
+		var nv V
+		if nv, err = toInteger[V](val, any(old)); err != nil {
+			return old, false
+		}
+		return nv, true
+	}, opts...)
+	if ok {
+		c.stored(key, nv, exp)
+	}
+	return val, err
+}
+
+func (c *cache[K, V]) Decr(key K, delta int64, opts ...ItemOption) (val int64, err error) {
+	return c.Incr(key, -delta, opts...)
+}
+
+func (c *cache[K, V]) CompareAndSwap(key K, old, new V, opts ...ItemOption) (swapped bool) {
+	if c.isClosed() {
+		return false
+	}
+	c.warm(key)
+	s := c.getShard(key)
+	_, exp, swapped := s.Update(c.opts, key, func(cur V, has bool) (V, bool) {
+		return new, has && equal(any(cur), any(old))
+	}, opts...)
+	if swapped {
+		c.stored(key, new, exp)
+	}
+	return swapped
+}
+
+func (c *cache[K, V]) Update(key K, fn func(old V, has bool) (V, bool), opts ...ItemOption) (val V, ok bool) {
+	if c.isClosed() {
+		return val, false
+	}
+	c.warm(key)
+	s := c.getShard(key)
+	val, exp, ok := s.Update(c.opts, key, fn, opts...)
+	if ok {
+		c.stored(key, val, exp)
+	}
+	return val, ok
+}
+
+/*
+  Package private
+*/
+
+// equal compares a and b with ==, uncomparable values are never equal.
+func equal(a, b any) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if !reflect.TypeOf(a).Comparable() || !reflect.TypeOf(b).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// toInteger converts n to V, an interface V keeps the integer type of old,
+// or uses int64.
+func toInteger[V any](n int64, old any) (val V, err error) {
+	typ := reflect.TypeOf((*V)(nil)).Elem()
+	if typ.Kind() == reflect.Interface {
+		typ = reflect.TypeOf(n)
+		if old != nil && isInteger(reflect.TypeOf(old).Kind()) {
+			typ = reflect.TypeOf(old)
+		}
+	}
+	if !isInteger(typ.Kind()) {
+		return val, fmt.Errorf("%w: %s", ErrNotInteger, typ)
+	}
+	val, ok := reflect.ValueOf(n).Convert(typ).Interface().(V)
+	if !ok {
+		return val, fmt.Errorf("%w: %s", ErrNotInteger, typ)
+	}
+	return val, nil
+}
+
+func isInteger(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
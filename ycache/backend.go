@@ -0,0 +1,135 @@
+package ycache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("ycache: key not found")
+
+// Backend shared second tier store, ttl <= 0 means no expiry.
+type Backend interface {
+	// Get returns the value and remaining time of the key, or ErrNotFound.
+	Get(key string) (val []byte, ttl time.Duration, err error)
+	// Set stores the key-value.
+	Set(key string, val []byte, ttl time.Duration) error
+	// Del deletes the key, a missing key is not an error.
+	Del(key string) error
+	// TTL returns the remaining time of the key, or ErrNotFound.
+	TTL(key string) (ttl time.Duration, err error)
+}
+
+// Broadcaster delivers messages to every subscriber, including the publisher.
+type Broadcaster interface {
+	// Publish sends msg to all subscribers.
+	Publish(msg []byte) error
+	// Subscribe calls fn for each message until cancel is called.
+	Subscribe(fn func(msg []byte)) (cancel func(), err error)
+}
+
+type memEntry struct {
+	val []byte
+	exp time.Time
+}
+
+// MemoryBackend in-process reference Backend and Broadcaster.
+type MemoryBackend struct {
+	mu   sync.Mutex
+	dict map[string]memEntry
+	seq  int
+	subs map[int]func(msg []byte)
+}
+
+func (m *MemoryBackend) Get(key string) (val []byte, ttl time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.get(key)
+	if !ok {
+		return nil, 0, ErrNotFound
+	}
+	return append([]byte(nil), e.val...), remain(e.exp), nil
+}
+
+func (m *MemoryBackend) Set(key string, val []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := memEntry{val: append([]byte(nil), val...)}
+	if ttl > 0 {
+		e.exp = time.Now().Add(ttl)
+	}
+	m.dict[key] = e
+	return nil
+}
+
+func (m *MemoryBackend) Del(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.dict, key)
+	return nil
+}
+
+func (m *MemoryBackend) TTL(key string) (ttl time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.get(key)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return remain(e.exp), nil
+}
+
+func (m *MemoryBackend) Publish(msg []byte) error {
+	m.mu.Lock()
+	subs := make([]func([]byte), 0, len(m.subs))
+	for _, fn := range m.subs {
+		subs = append(subs, fn)
+	}
+	m.mu.Unlock()
+	for _, fn := range subs {
+		fn(msg)
+	}
+	return nil
+}
+
+func (m *MemoryBackend) Subscribe(fn func(msg []byte)) (cancel func(), err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	id := m.seq
+	m.subs[id] = fn
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.subs, id)
+	}, nil
+}
+
+// NewMemoryBackend returns an empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		dict: make(map[string]memEntry),
+		subs: make(map[int]func(msg []byte)),
+	}
+}
+
+/*
+  Package private
+*/
+
+func (m *MemoryBackend) get(key string) (memEntry, bool) {
+	e, ok := m.dict[key]
+	if ok && !e.exp.IsZero() && !e.exp.After(time.Now()) {
+		delete(m.dict, key)
+		return e, false
+	}
+	return e, ok
+}
+
+// remain returns the time until exp, 0 if exp is zero.
+func remain(exp time.Time) time.Duration {
+	if exp.IsZero() {
+		return 0
+	}
+	return max(time.Until(exp), time.Nanosecond)
+}
@@ -1,92 +1,198 @@
 package ycache
 
 import (
-	"hash/fnv"
+	"errors"
+	"io"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/azeroth-sha/y/internal"
 	"github.com/azeroth-sha/y/ylog"
 )
 
 // Cache interface
-type Cache interface {
+type Cache = TypedCache[string, any]
+
+// TypedCache generic cache interface
+type TypedCache[K comparable, V any] interface {
 	// Has returns whether the key exists.
-	Has(key string) (has bool)
+	Has(key K) (has bool)
 	// Set key-value, if the key exists, it will be overwritten.
-	Set(key string, val any, opts ...ItemOption)
+	Set(key K, val V, opts ...ItemOption)
 	// Get returns the value of the key, if the key does not exist, it returns false.
-	Get(key string) (val any, has bool)
+	Get(key K) (val V, has bool)
 	// Del deletes the key.
-	Del(key string) (ok bool)
+	Del(key K) (ok bool)
 	// GetDel returns the value of the key and deletes the key.
-	GetDel(key string) (val any, has bool)
+	GetDel(key K) (val V, has bool)
 	// GetSet returns the value of the key and sets the new value.
-	GetSet(key string, newVal any, opts ...ItemOption) (oldVal any, has bool)
+	GetSet(key K, newVal V, opts ...ItemOption) (oldVal V, has bool)
 	// SetX sets the key-value, if the key exists, it will not be overwritten.
-	SetX(key string, val any, opts ...ItemOption) (ok bool)
+	SetX(key K, val V, opts ...ItemOption) (ok bool)
 	// DelExpired deletes the expired key.
-	DelExpired(key string) (has, exp bool)
+	DelExpired(key K) (has, exp bool)
 	// All returns all key-value.
-	All() (kvs map[string]any)
+	All() (kvs map[K]V)
 	// Len returns the number of key-value.
 	Len() int
 	// Clear deletes all key-value.
 	Clear() int
 	// TTL returns the remaining time of the key.
-	TTL(key string) (ttl time.Duration, has bool)
+	TTL(key K) (ttl time.Duration, has bool)
 	// Expire sets the remaining time of the key.
-	Expire(key string, ttl time.Duration) (ok bool)
+	Expire(key K, ttl time.Duration) (ok bool)
+	// GetOrLoad returns the value of the key, concurrent misses share one loader call.
+	GetOrLoad(key K, loader func() (V, time.Duration, error)) (val V, err error)
+	// Snapshot writes all live key-value with their expire time to w.
+	Snapshot(w io.Writer) error
+	// Restore loads key-value written by Snapshot, expired ones are skipped.
+	Restore(r io.Reader) error
+	// Stats returns the cache statistics.
+	Stats() Stats
+	// ResetStats returns the cache statistics and resets the counters.
+	ResetStats() Stats
+	// Incr adds delta to the integer value of the key, a missing key counts as 0.
+	Incr(key K, delta int64, opts ...ItemOption) (val int64, err error)
+	// Decr subtracts delta from the integer value of the key, a missing key counts as 0.
+	Decr(key K, delta int64, opts ...ItemOption) (val int64, err error)
+	// CompareAndSwap sets the key to new if its value equals old.
+	CompareAndSwap(key K, old, new V, opts ...ItemOption) (swapped bool)
+	// Update stores the value returned by fn if it also returns true, fn runs
+	// under the shard lock and must not use the cache. Existing keys keep their
+	// item options unless opts are given.
+	Update(key K, fn func(old V, has bool) (V, bool), opts ...ItemOption) (val V, ok bool)
+	// Range calls fn for each live key-value until it returns false, shards
+	// are copied one at a time so fn may use the cache.
+	Range(fn func(key K, val V) bool)
+	// Scan returns about count keys matching the glob pattern and the cursor
+	// of the next call, iteration starts and ends with cursor 0. Keys present
+	// during the whole iteration are returned at least once.
+	Scan(cursor uint64, match string, count int) (keys []K, next uint64)
+	// Keys returns the keys matching the glob pattern.
+	Keys(pattern string) (keys []K)
+	// DelPrefix deletes the keys starting with prefix.
+	DelPrefix(prefix string) int
+	// InvalidateTag deletes the keys set with the tag, see WithItemTags.
+	InvalidateTag(tag string) int
+	// KeysByTag returns the keys set with the tag, see WithItemTags.
+	KeysByTag(tag string) (keys []K)
+	// Subscribe returns the changes of keys matching the glob pattern,
+	// see Match, until cancel or Close is called.
+	Subscribe(pattern string) (events <-chan TypedEvent[K, V], cancel func())
+	// Close stops the background work, later calls behave as on an empty
+	// cache and the ones returning an error return ErrClosed.
+	io.Closer
 }
 
-type cache struct {
+var ErrClosed = errors.New("ycache: cache closed")
+
+type cache[K comparable, V any] struct {
 	opts   *options
-	bucket []*shard
+	hash   func(K) uint32
+	str    func(K) string
+	bucket []*shard[K, V]
+	flight *flight[K, V]
+	hub    *hub[K, V]
+	tier   *tier[K, V]
 	closed chan struct{}
+	down   atomic.Bool
+	wait   sync.WaitGroup
+}
+
+// handle is returned by NewTyped, background goroutines only reference the
+// cache so the finalizer of an unreachable handle can close it.
+type handle[K comparable, V any] struct {
+	*cache[K, V]
 }
 
-func (c *cache) Has(key string) (has bool) {
+func (c *cache[K, V]) Has(key K) (has bool) {
+	if c.isClosed() {
+		return false
+	}
+	if c.tier != nil {
+		_, has = c.Get(key)
+		return has
+	}
 	s := c.getShard(key)
 	return s.Has(c.opts, key)
 }
 
-func (c *cache) Set(key string, val any, opts ...ItemOption) {
+func (c *cache[K, V]) Set(key K, val V, opts ...ItemOption) {
+	if c.isClosed() {
+		return
+	}
 	s := c.getShard(key)
-	s.Set(c.opts, key, val, opts...)
+	exp := s.Set(c.opts, key, val, opts...)
+	c.stored(key, val, exp)
 }
 
-func (c *cache) Get(key string) (val any, has bool) {
+func (c *cache[K, V]) Get(key K) (val V, has bool) {
+	if c.isClosed() {
+		return val, false
+	}
 	s := c.getShard(key)
-	return s.Get(c.opts, key)
+	if val, has = s.Get(c.opts, key); !has {
+		return c.readThrough(key)
+	}
+	return val, has
 }
 
-func (c *cache) Del(key string) (ok bool) {
+func (c *cache[K, V]) Del(key K) (ok bool) {
+	if c.isClosed() {
+		return false
+	}
 	s := c.getShard(key)
-	return s.Del(c.opts, key)
+	ok = s.Del(c.opts, key)
+	c.deleted(key)
+	return ok
 }
 
-func (c *cache) GetDel(key string) (val any, has bool) {
+func (c *cache[K, V]) GetDel(key K) (val V, has bool) {
+	if c.isClosed() {
+		return val, false
+	}
+	c.warm(key)
 	s := c.getShard(key)
-	return s.GetDel(c.opts, key)
+	val, has = s.GetDel(c.opts, key)
+	c.deleted(key)
+	return val, has
 }
 
-func (c *cache) GetSet(key string, newVal any, opts ...ItemOption) (oldVal any, has bool) {
+func (c *cache[K, V]) GetSet(key K, newVal V, opts ...ItemOption) (oldVal V, has bool) {
+	if c.isClosed() {
+		return oldVal, false
+	}
+	c.warm(key)
 	s := c.getShard(key)
-	return s.GetSet(c.opts, key, newVal, opts...)
+	oldVal, exp, has := s.GetSet(c.opts, key, newVal, opts...)
+	c.stored(key, newVal, exp)
+	return oldVal, has
 }
 
-func (c *cache) SetX(key string, val any, opts ...ItemOption) (ok bool) {
+func (c *cache[K, V]) SetX(key K, val V, opts ...ItemOption) (ok bool) {
+	if c.isClosed() {
+		return false
+	}
+	c.warm(key)
 	s := c.getShard(key)
-	return s.SetX(c.opts, key, val, opts...)
+	exp, ok := s.SetX(c.opts, key, val, opts...)
+	if ok {
+		c.stored(key, val, exp)
+	}
+	return ok
 }
 
-func (c *cache) DelExpired(key string) (has, exp bool) {
+func (c *cache[K, V]) DelExpired(key K) (has, exp bool) {
+	if c.isClosed() {
+		return false, false
+	}
 	s := c.getShard(key)
 	return s.DelExpired(c.opts, key)
 }
 
-func (c *cache) All() (kvs map[string]any) {
-	kvs = make(map[string]any)
+func (c *cache[K, V]) All() (kvs map[K]V) {
+	kvs = make(map[K]V)
 	for _, s := range c.bucket {
 		shardKvs := s.All(c.opts)
 		for k, v := range shardKvs {
@@ -96,7 +202,7 @@ func (c *cache) All() (kvs map[string]any) {
 	return kvs
 }
 
-func (c *cache) Len() int {
+func (c *cache[K, V]) Len() int {
 	count := 0
 	for _, s := range c.bucket {
 		count += s.Len(c.opts)
@@ -104,7 +210,7 @@ func (c *cache) Len() int {
 	return count
 }
 
-func (c *cache) Clear() int {
+func (c *cache[K, V]) Clear() int {
 	count := 0
 	for _, s := range c.bucket {
 		count += s.Clear(c.opts)
@@ -112,52 +218,195 @@ func (c *cache) Clear() int {
 	return count
 }
 
-func (c *cache) TTL(key string) (ttl time.Duration, has bool) {
+func (c *cache[K, V]) TTL(key K) (ttl time.Duration, has bool) {
+	if c.isClosed() {
+		return 0, false
+	}
 	s := c.getShard(key)
-	return s.TTL(c.opts, key)
+	if ttl, has = s.TTL(c.opts, key); !has {
+		if _, has = c.readThrough(key); has {
+			return s.TTL(c.opts, key)
+		}
+	}
+	return ttl, has
 }
 
-func (c *cache) Expire(key string, ttl time.Duration) (ok bool) {
+func (c *cache[K, V]) Expire(key K, ttl time.Duration) (ok bool) {
+	if c.isClosed() {
+		return false
+	}
+	c.warm(key)
 	s := c.getShard(key)
-	return s.Expire(c.opts, key, ttl)
+	val, exp, ok := s.Expire(c.opts, key, ttl)
+	if ok {
+		c.stored(key, val, exp)
+	}
+	return ok
+}
+
+func (c *cache[K, V]) GetOrLoad(key K, loader func() (V, time.Duration, error)) (val V, err error) {
+	if c.isClosed() {
+		return val, ErrClosed
+	}
+	s := c.getShard(key)
+	val, exp, has := s.GetExp(c.opts, key)
+	if has {
+		if ahead := c.opts.refresh; ahead > 0 && !exp.IsZero() && time.Until(exp) < ahead {
+			_, _ = c.flight.Do(c, key, loader, true)
+		}
+		return val, nil
+	}
+	if val, has = c.readThrough(key); has {
+		return val, nil
+	}
+	if err, has = c.flight.Negative(key); has {
+		return val, err
+	}
+	return c.flight.Do(c, key, loader, false)
+}
+
+func (c *cache[K, V]) Subscribe(pattern string) (events <-chan TypedEvent[K, V], cancel func()) {
+	return c.hub.Subscribe(pattern)
 }
 
+func (c *cache[K, V]) Stats() Stats {
+	return c.stats(false)
+}
+
+func (c *cache[K, V]) ResetStats() Stats {
+	return c.stats(true)
+}
+
+func (c *cache[K, V]) Close() error {
+	if !c.down.CompareAndSwap(false, true) {
+		return ErrClosed
+	}
+	close(c.closed)
+	c.wait.Wait()
+	if c.opts.snapPath != "" && c.opts.snapEvery > 0 {
+		if err := c.saveFile(c.opts.snapPath); err != nil {
+			c.opts.log.Errorf("ycache: snapshot error: %v", err)
+		}
+	}
+	for _, s := range c.bucket {
+		s.Flush(c.opts, c.opts.closeFun)
+	}
+	c.hub.Close()
+	if c.tier != nil {
+		c.tier.Close()
+	}
+	return nil
+}
+
+// New returns a new cache
 func New(opts ...Option) Cache {
+	return NewTyped[string, any](opts...)
+}
+
+// NewTyped returns a new cache with typed keys and values.
+func NewTyped[K comparable, V any](opts ...Option) TypedCache[K, V] {
 	cnf := &options{
 		interval:  DefaultInterval,
 		expire:    nil,
 		shardSize: runtime.NumCPU() * 4,
+		batch:     DefaultBatch,
+		eventSize: DefaultEventBuffer,
 		log:       ylog.DefaultLog(),
+		codec:     GobCodec{},
 	}
 	for _, opt := range opts {
 		opt(cnf)
 	}
-	bucket := &cache{
+	cnf.log = ylog.Named(cnf.log, `ycache`)
+	bucket := &cache[K, V]{
 		opts:   cnf,
-		bucket: make([]*shard, 0, cnf.shardSize),
+		hash:   newHasher[K](),
+		str:    newStringer[K](),
+		bucket: make([]*shard[K, V], 0, cnf.shardSize),
+		flight: newFlight[K, V](),
+		hub:    newHub[K, V](cnf),
 		closed: make(chan struct{}),
 	}
+	bucket.checkExpire()
+	newEvictor := bucket.evictor()
+	sizer := bucket.sizer()
 	for i := 0; i < cnf.shardSize; i++ {
-		bucket.bucket = append(bucket.bucket, newShard())
+		var evict Evictor[K]
+		if newEvictor != nil {
+			evict = newEvictor()
+		}
+		bucket.bucket = append(bucket.bucket, newShard[K, V](cnf, evict, bucket.hub, sizer, bucket.hash))
 	}
+	bucket.tier = newTier(bucket)
+	if cnf.snapPath != "" {
+		if err := bucket.loadFile(cnf.snapPath); err != nil {
+			cnf.log.Errorf("ycache: restore error: %v", err)
+		}
+		if cnf.snapEvery > 0 {
+			bucket.wait.Add(1)
+			go bucket.snapshotter()
+		}
+	}
+	bucket.wait.Add(1)
 	go bucket.ticker()
-	runtime.SetFinalizer(bucket, func(c *cache) {
-		close(c.closed)
+	h := &handle[K, V]{cache: bucket}
+	runtime.SetFinalizer(h, func(h *handle[K, V]) {
+		_ = h.Close()
 	})
-	return bucket
+	return h
 }
 
 /*
   Package private
 */
 
-func (c *cache) getShard(k string) *shard {
-	h := fnv.New32a()
-	_, _ = h.Write(internal.ToBytes(k))
-	return c.bucket[int(h.Sum32())%c.opts.shardSize]
+func (c *cache[K, V]) getShard(k K) *shard[K, V] {
+	return c.bucket[int(c.hash(k)%uint32(c.opts.shardSize))]
+}
+
+func (c *cache[K, V]) isClosed() bool {
+	return c.down.Load()
+}
+
+func (c *cache[K, V]) stats(reset bool) Stats {
+	st := Stats{Shards: make([]int, len(c.bucket)), ShardBytes: make([]int64, len(c.bucket))}
+	for n, s := range c.bucket {
+		s.stat.AddTo(&st, reset)
+		st.Shards[n] = s.Len(c.opts)
+		st.ShardBytes[n] = s.Bytes(c.opts)
+		st.Bytes += st.ShardBytes[n]
+	}
+	if reset {
+		st.Dropped = c.hub.dropped.Swap(0)
+	} else {
+		st.Dropped = c.hub.dropped.Load()
+	}
+	return st
+}
+
+func (c *cache[K, V]) evictor() func() Evictor[K] {
+	f, ok := c.opts.evictor.(func() Evictor[K])
+	if c.opts.evictor != nil && !ok {
+		c.opts.log.Errorf("ycache: evictor type mismatch: %T", c.opts.evictor)
+	}
+	if f == nil && (c.opts.maxItems > 0 || c.opts.maxCost > 0 || c.opts.maxBytes > 0) {
+		f = NewLRU[K]
+	}
+	return f
+}
+
+// checkExpire drops a default callback not matching K and V, it would never be called.
+func (c *cache[K, V]) checkExpire() {
+	switch c.opts.expire.(type) {
+	case nil, TypedExpireFun[K, V], TypedEvictFun[K, V]:
+	default:
+		c.opts.log.Errorf("ycache: expire callback type mismatch: %T", c.opts.expire)
+		c.opts.expire = nil
+	}
 }
 
-func (c *cache) ticker() {
+func (c *cache[K, V]) ticker() {
+	defer c.wait.Done()
 	dur := c.opts.interval
 	if dur <= 0 {
 		return
@@ -175,8 +424,9 @@ EXIT:
 	}
 }
 
-func (c *cache) checkAll() {
+func (c *cache[K, V]) checkAll() {
 	for _, s := range c.bucket {
-		s.Check(c.opts)
+		s.Check(c.opts, c.opts.batch)
 	}
+	c.flight.Check()
 }
@@ -0,0 +1,74 @@
+package ycache
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// settle waits for the goroutine count to drop to want.
+func settle(t *testing.T, want int, gc bool) {
+	t.Helper()
+	n := runtime.NumGoroutine()
+	for deadline := time.Now().Add(2 * time.Second); n > want && time.Now().Before(deadline); n = runtime.NumGoroutine() {
+		if gc {
+			runtime.GC()
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n > want {
+		buf := make([]byte, 1<<16)
+		t.Fatalf("%d goroutines, want %d\n%s", n, want, buf[:runtime.Stack(buf, true)])
+	}
+}
+
+func TestCloseLeak(t *testing.T) {
+	base := runtime.NumGoroutine()
+	c := NewTyped[string, int](WithInterval(time.Millisecond), WithSnapshot(filepath.Join(t.TempDir(), `cache.snap`), time.Millisecond))
+	_, cancel := c.Subscribe(`*`)
+	defer cancel()
+	c.Set(`a`, 1)
+	if n := runtime.NumGoroutine(); n <= base {
+		t.Fatalf("%d goroutines after New, want more than %d", n, base)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != ErrClosed {
+		t.Errorf("second Close() = %v, want ErrClosed", err)
+	}
+	settle(t, base, false)
+}
+
+func TestFinalizerLeak(t *testing.T) {
+	base := runtime.NumGoroutine()
+	func() {
+		c := NewTyped[string, int](WithInterval(time.Millisecond), WithSnapshot(filepath.Join(t.TempDir(), `cache.snap`), time.Millisecond))
+		c.Set(`a`, 1)
+	}()
+	settle(t, base, true)
+}
+
+func TestCloseSnapshot(t *testing.T) {
+	name := filepath.Join(t.TempDir(), `cache.snap`)
+	c := NewTyped[string, int](WithSnapshot(name, time.Hour))
+	c.Set(`a`, 1)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := NewTyped[string, int](WithSnapshot(name, time.Hour))
+	defer r.Close()
+	if v, has := r.Get(`a`); !has || v != 1 {
+		t.Errorf("Get(a) = %d, %v after restart, want 1, true", v, has)
+	}
+}
+
+func TestNew(t *testing.T) {
+	var c Cache = New(WithInterval(time.Hour))
+	defer c.Close()
+	c.Set(`a`, `v`)
+	if v, has := c.Get(`a`); !has || v != `v` {
+		t.Errorf("Get(a) = %v, %v, want v, true", v, has)
+	}
+}
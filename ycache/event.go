@@ -0,0 +1,160 @@
+package ycache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Op kind of key-value change
+type Op uint8
+
+const (
+	OpSet       Op = iota + 1 // new key stored
+	OpOverwrite               // existing key stored
+	OpDelete                  // key deleted
+	OpExpire                  // key expired
+	OpEvict                   // key evicted
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpSet:
+		return `set`
+	case OpOverwrite:
+		return `overwrite`
+	case OpDelete:
+		return `delete`
+	case OpExpire:
+		return `expire`
+	case OpEvict:
+		return `evict`
+	default:
+		return `unknown`
+	}
+}
+
+// DropPolicy what a full subscriber buffer drops
+type DropPolicy uint8
+
+const (
+	DropNewest DropPolicy = iota // drop the new event
+	DropOldest                   // drop the oldest buffered event
+)
+
+const DefaultEventBuffer = 128
+
+// Event key-value change
+type Event = TypedEvent[string, any]
+
+// TypedEvent generic key-value change
+type TypedEvent[K comparable, V any] struct {
+	Op  Op
+	Key K
+	Val V
+}
+
+type subscriber[K comparable, V any] struct {
+	mu      sync.Mutex // serializes DropOldest
+	pattern string
+	ch      chan TypedEvent[K, V]
+}
+
+// hub dispatches events to subscribers, Publish never blocks.
+type hub[K comparable, V any] struct {
+	mu      sync.RWMutex
+	subs    map[*subscriber[K, V]]struct{}
+	count   atomic.Int32
+	dropped atomic.Uint64
+	size    int
+	policy  DropPolicy
+	toStr   func(K) string
+}
+
+func (h *hub[K, V]) Subscribe(pattern string) (<-chan TypedEvent[K, V], func()) {
+	sub := &subscriber[K, V]{
+		pattern: pattern,
+		ch:      make(chan TypedEvent[K, V], h.size),
+	}
+	h.mu.Lock()
+	if h.subs == nil {
+		h.mu.Unlock()
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	h.subs[sub] = struct{}{}
+	h.count.Add(1)
+	h.mu.Unlock()
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() { h.cancel(sub) })
+	}
+}
+
+func (h *hub[K, V]) Publish(op Op, key K, val V) {
+	if h.count.Load() == 0 {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	str := h.toStr(key)
+	for sub := range h.subs {
+		if sub.pattern == "*" || Match(sub.pattern, str) {
+			h.send(sub, TypedEvent[K, V]{Op: op, Key: key, Val: val})
+		}
+	}
+}
+
+// Close closes all subscriptions, later ones are closed at once.
+func (h *hub[K, V]) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		close(sub.ch)
+	}
+	h.subs = nil
+	h.count.Store(0)
+}
+
+/*
+  Package private
+*/
+
+func (h *hub[K, V]) send(sub *subscriber[K, V], e TypedEvent[K, V]) {
+	select {
+	case sub.ch <- e:
+		return
+	default:
+	}
+	if h.policy == DropOldest {
+		sub.mu.Lock()
+		defer sub.mu.Unlock()
+		select {
+		case <-sub.ch:
+		default:
+		}
+		select {
+		case sub.ch <- e:
+		default:
+		}
+	}
+	h.dropped.Add(1)
+}
+
+func (h *hub[K, V]) cancel(sub *subscriber[K, V]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, y := h.subs[sub]; y {
+		delete(h.subs, sub)
+		h.count.Add(-1)
+		close(sub.ch)
+	}
+}
+
+func newHub[K comparable, V any](cnf *options) *hub[K, V] {
+	return &hub[K, V]{
+		subs:   make(map[*subscriber[K, V]]struct{}),
+		size:   cnf.eventSize,
+		policy: cnf.dropPolicy,
+		toStr:  newStringer[K](),
+	}
+}
@@ -0,0 +1,37 @@
+package ycache
+
+// Reason why an item left the cache
+type Reason uint8
+
+const (
+	ReasonExpired Reason = iota + 1 // ttl reached
+	ReasonEvicted                   // removed by the eviction policy
+	ReasonClosed                    // removed by Close
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonExpired:
+		return `expired`
+	case ReasonEvicted:
+		return `evicted`
+	case ReasonClosed:
+		return `closed`
+	default:
+		return `unknown`
+	}
+}
+
+// Evictor shard eviction policy, all calls are serialized by the shard.
+type Evictor[K comparable] interface {
+	// Add records a new key.
+	Add(key K)
+	// Touch records an access of the key.
+	Touch(key K)
+	// Del forgets the key.
+	Del(key K)
+	// Victim returns the next key to evict.
+	Victim() (key K, ok bool)
+	// Admit reports whether key may be stored at the cost of victim.
+	Admit(key, victim K) bool
+}
@@ -0,0 +1,213 @@
+package ycache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func victim(t *testing.T, e Evictor[string], want string) {
+	t.Helper()
+	if k, ok := e.Victim(); !ok || k != want {
+		t.Errorf("Victim() = %q, %v, want %q, true", k, ok, want)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	e := NewLRU[string]()
+	if _, ok := e.Victim(); ok {
+		t.Error("Victim() of an empty LRU = true")
+	}
+	e.Add(`a`)
+	e.Add(`b`)
+	e.Add(`c`)
+	victim(t, e, `a`)
+	e.Touch(`a`)
+	victim(t, e, `b`)
+	e.Del(`b`)
+	victim(t, e, `c`)
+	e.Add(`c`)
+	victim(t, e, `a`)
+}
+
+func TestLFU(t *testing.T) {
+	e := NewLFU[string]()
+	if _, ok := e.Victim(); ok {
+		t.Error("Victim() of an empty LFU = true")
+	}
+	e.Add(`a`)
+	e.Add(`b`)
+	e.Add(`c`)
+	e.Touch(`a`)
+	e.Touch(`a`)
+	e.Touch(`c`)
+	victim(t, e, `b`)
+	e.Del(`b`)
+	victim(t, e, `c`)
+	e.Add(`d`)
+	e.Add(`e`)
+	victim(t, e, `d`) // ties go to the least recent
+	e.Touch(`d`)
+	victim(t, e, `e`)
+	e.Del(`e`)
+	e.Touch(`c`)
+	victim(t, e, `d`)
+}
+
+func TestTinyLFU(t *testing.T) {
+	e := NewTinyLFU[string]()
+	e.Add(`hot`)
+	e.Touch(`hot`)
+	e.Touch(`hot`)
+	victim(t, e, `hot`)
+	admitted := 0
+	for n := 1; n <= 4; n++ {
+		if e.Admit(`new`, `hot`) {
+			admitted = n
+			break
+		}
+	}
+	if admitted != 4 {
+		t.Errorf("new key admitted on call %d, want 4 once used more than the victim", admitted)
+	}
+}
+
+type evicted struct {
+	key    string
+	val    int
+	reason Reason
+}
+
+// evictCache returns a single shard cache recording the removals.
+func evictCache(t *testing.T, opts ...Option) (TypedCache[string, int], func() []evicted) {
+	t.Helper()
+	mu := new(sync.Mutex)
+	var got []evicted
+	opts = append([]Option{
+		WithInterval(time.Hour),
+		WithShardSize(1),
+		WithEvictFun(func(key string, val int, reason Reason) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, evicted{key: key, val: val, reason: reason})
+		}),
+	}, opts...)
+	c := NewTyped[string, int](opts...)
+	t.Cleanup(func() { _ = c.Close() })
+	return c, func() []evicted {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]evicted(nil), got...)
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	c, got := evictCache(t, WithMaxEntries(2))
+	c.Set(`a`, 1)
+	c.Set(`b`, 2)
+	c.Get(`a`)
+	c.Set(`c`, 3)
+	if c.Has(`b`) || !c.Has(`a`) || !c.Has(`c`) {
+		t.Errorf("keys %v, want a and c", c.All())
+	}
+	if g := got(); len(g) != 1 || g[0] != (evicted{`b`, 2, ReasonEvicted}) {
+		t.Errorf("evicted %v, want [{b 2 evicted}]", g)
+	}
+	c.Set(`a`, 10) // overwrites do not evict
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len() = %d after overwrite, want 2", n)
+	}
+	if st := c.Stats(); st.Evictions != 1 {
+		t.Errorf("Stats().Evictions = %d, want 1", st.Evictions)
+	}
+}
+
+func TestMaxCost(t *testing.T) {
+	c, got := evictCache(t, WithMaxCost(10))
+	c.Set(`a`, 1, WithItemCost(4))
+	c.Set(`b`, 2, WithItemCost(4))
+	c.Set(`c`, 3, WithItemCost(4))
+	if c.Has(`a`) || !c.Has(`b`) || !c.Has(`c`) {
+		t.Errorf("keys %v, want b and c", c.All())
+	}
+	c.Set(`b`, 20, WithItemCost(8)) // growing an item evicts others
+	if c.Has(`c`) {
+		t.Errorf("keys %v, want b", c.All())
+	}
+	want := []evicted{{`a`, 1, ReasonEvicted}, {`c`, 3, ReasonEvicted}}
+	if g := got(); len(g) != len(want) || g[0] != want[0] || g[1] != want[1] {
+		t.Errorf("evicted %v, want %v", g, want)
+	}
+}
+
+func TestRefusedInsert(t *testing.T) {
+	c, got := evictCache(t, WithMaxCost(10))
+	c.Set(`new`, 1, WithItemCost(100))
+	if c.Has(`new`) {
+		t.Error("Has(new) = true, an item over the max cost was stored")
+	}
+	if !c.SetX(`x`, 1, WithItemCost(1)) || c.SetX(`y`, 1, WithItemCost(100)) {
+		t.Error("SetX stored an item over the max cost")
+	}
+	c.Set(`a`, 1, WithItemCost(1))
+	c.Set(`a`, 2, WithItemCost(100))
+	if v, has := c.Get(`a`); has {
+		t.Errorf("Get(a) = %d, true after a refused overwrite, want the old value gone", v)
+	}
+	c.Set(`b`, 1, WithItemCost(1))
+	if old, has := c.GetSet(`b`, 2, WithItemCost(100)); !has || old != 1 {
+		t.Errorf("GetSet(b) = %d, %v, want 1, true", old, has)
+	}
+	if v, has := c.Get(`b`); has {
+		t.Errorf("Get(b) = %d, true after a refused GetSet, want the old value gone", v)
+	}
+	want := []evicted{{`a`, 1, ReasonEvicted}, {`b`, 1, ReasonEvicted}}
+	if g := got(); len(g) != len(want) || g[0] != want[0] || g[1] != want[1] {
+		t.Errorf("evicted %v, want %v", g, want)
+	}
+	if !c.Has(`x`) {
+		t.Error("Has(x) = false, refused inserts evicted an unrelated key")
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	c, _ := evictCache(t, WithMaxBytes(100), WithSizer(func(val int) int { return val }))
+	c.Set(`a`, 60)
+	c.Set(`b`, 30)
+	c.Set(`c`, 30)
+	if c.Has(`a`) || !c.Has(`b`) || !c.Has(`c`) {
+		t.Errorf("keys %v, want b and c", c.All())
+	}
+	c.Set(`b`, 200)
+	if c.Has(`b`) {
+		t.Error("Has(b) = true after an overwrite over the max bytes")
+	}
+}
+
+func TestEvictorAdmission(t *testing.T) {
+	c, got := evictCache(t, WithMaxEntries(1), WithEvictor(NewTinyLFU[string]))
+	c.Set(`hot`, 1)
+	for i := 0; i < 5; i++ {
+		c.Get(`hot`)
+	}
+	c.Set(`cold`, 2)
+	if !c.Has(`hot`) || c.Has(`cold`) {
+		t.Errorf("keys %v, want hot kept and cold refused", c.All())
+	}
+	if g := got(); len(g) != 0 {
+		t.Errorf("evicted %v, want none", g)
+	}
+}
+
+func TestLFUCache(t *testing.T) {
+	c, got := evictCache(t, WithMaxEntries(2), WithEvictor(NewLFU[string]))
+	c.Set(`a`, 1)
+	c.Set(`b`, 2)
+	c.Get(`a`)
+	c.Get(`a`)
+	c.Get(`b`)
+	c.Set(`c`, 3)
+	if g := got(); len(g) != 1 || g[0].key != `b` {
+		t.Errorf("evicted %v, want b", g)
+	}
+}
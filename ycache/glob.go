@@ -0,0 +1,90 @@
+package ycache
+
+// Match reports whether str matches the glob pattern, supported syntax:
+//
+//	'*'      any sequence of characters, including none
+//	'?'      any single character
+//	'[abc]'  one of the characters, [^abc] negates, [a-z] is a range
+//	'\c'     the literal character c
+func Match(pattern, str string) bool {
+	p, s := []rune(pattern), []rune(str)
+	// position to resume from after the last '*'
+	starP, starS := -1, 0
+	for pi, si := 0, 0; si < len(s) || pi < len(p); {
+		if pi < len(p) {
+			switch p[pi] {
+			case '*':
+				starP, starS = pi, si
+				pi++
+				continue
+			case '?':
+				if si < len(s) {
+					pi++
+					si++
+					continue
+				}
+			case '[':
+				if si < len(s) {
+					if ok, n := matchClass(p[pi:], s[si]); ok {
+						pi += n
+						si++
+						continue
+					}
+				}
+			case '\\':
+				if pi+1 < len(p) && si < len(s) && p[pi+1] == s[si] {
+					pi += 2
+					si++
+					continue
+				}
+			default:
+				if si < len(s) && p[pi] == s[si] {
+					pi++
+					si++
+					continue
+				}
+			}
+		}
+		if starP < 0 || starS >= len(s) {
+			return false
+		}
+		starS++
+		pi, si = starP+1, starS
+	}
+	return true
+}
+
+/*
+  Package private
+*/
+
+// matchClass matches c against the class at the start of p,
+// it returns the class length in runes.
+func matchClass(p []rune, c rune) (ok bool, n int) {
+	i := 1
+	neg := i < len(p) && p[i] == '^'
+	if neg {
+		i++
+	}
+	for first := true; i < len(p); first = false {
+		if p[i] == ']' && !first {
+			return ok != neg, i + 1
+		}
+		lo := p[i]
+		if lo == '\\' && i+1 < len(p) {
+			i++
+			lo = p[i]
+		}
+		hi := lo
+		if i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']' {
+			hi = p[i+2]
+			i += 2
+		}
+		if lo <= c && c <= hi {
+			ok = true
+		}
+		i++
+	}
+	// unterminated class, match '[' literally
+	return c == '[', 1
+}
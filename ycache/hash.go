@@ -0,0 +1,123 @@
+package ycache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+
+	"github.com/azeroth-sha/y/internal"
+	"github.com/azeroth-sha/y/ysum"
+)
+
+// newHasher returns the shard hash function for keys of type K.
+func newHasher[K comparable]() func(K) uint32 {
+	typ := reflect.TypeOf((*K)(nil)).Elem()
+	switch typ.Kind() {
+	case reflect.String:
+		if typ == reflect.TypeOf("") {
+			return func(k K) uint32 {
+				return sumBytes(internal.ToBytes(any(k).(string)))
+			}
+		}
+		return func(k K) uint32 {
+			return sumBytes(internal.ToBytes(reflect.ValueOf(k).String()))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(k K) uint32 {
+			return sumUint64(uint64(reflect.ValueOf(k).Int()))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(k K) uint32 {
+			return sumUint64(reflect.ValueOf(k).Uint())
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(k K) uint32 {
+			f := reflect.ValueOf(k).Float()
+			if f == 0 {
+				f = 0 // -0 == +0
+			}
+			return sumUint64(math.Float64bits(f))
+		}
+	case reflect.Bool:
+		return func(k K) uint32 {
+			return sumUint64(internal.Or[uint64](reflect.ValueOf(k).Bool(), 1, 0))
+		}
+	default:
+		return func(k K) uint32 {
+			var buf [64]byte
+			return sumBytes(appendValue(buf[:0], reflect.ValueOf(&k).Elem()))
+		}
+	}
+}
+
+// appendValue appends the bytes of v so that keys equal under == append the
+// same bytes, blank struct fields are skipped and -0 is written as +0.
+func appendValue(b []byte, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.String:
+		b = binary.AppendUvarint(b, uint64(v.Len()))
+		return append(b, v.String()...)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.LittleEndian.AppendUint64(b, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.LittleEndian.AppendUint64(b, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return appendFloat(b, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return appendFloat(appendFloat(b, real(c)), imag(c))
+	case reflect.Bool:
+		return append(b, internal.Or[byte](v.Bool(), 1, 0))
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return binary.LittleEndian.AppendUint64(b, uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			return append(b, 0)
+		}
+		return appendValue(append(b, 1), v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			b = appendValue(b, v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).Name != "_" {
+				b = appendValue(b, v.Field(i))
+			}
+		}
+	}
+	return b
+}
+
+func appendFloat(b []byte, f float64) []byte {
+	if f == 0 {
+		f = 0 // -0 == +0
+	}
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+}
+
+func sumBytes(b []byte) uint32 {
+	return ysum.Sum32(fnv.New32a(), b)
+}
+
+func sumUint64(v uint64) uint32 {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return sumBytes(b[:])
+}
+
+// newStringer returns the function matching keys of type K against patterns.
+func newStringer[K comparable]() func(K) string {
+	var zero K
+	if _, ok := any(zero).(string); ok {
+		return func(k K) string {
+			return any(k).(string)
+		}
+	}
+	return func(k K) string {
+		return fmt.Sprint(k)
+	}
+}
@@ -0,0 +1,66 @@
+package ycache
+
+import (
+	"container/heap"
+	"time"
+)
+
+type expEntry[K comparable, V any] struct {
+	key K
+	i   *item[V]
+}
+
+// expHeap is a min-heap of items ordered by expire time.
+type expHeap[K comparable, V any] []expEntry[K, V]
+
+func (h expHeap[K, V]) Len() int { return len(h) }
+
+func (h expHeap[K, V]) Less(a, b int) bool { return h[a].i.Deadline().Before(h[b].i.Deadline()) }
+
+func (h expHeap[K, V]) Swap(a, b int) {
+	h[a], h[b] = h[b], h[a]
+	h[a].i.idx = a
+	h[b].i.idx = b
+}
+
+func (h *expHeap[K, V]) Push(x any) {
+	e := x.(expEntry[K, V])
+	e.i.idx = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expHeap[K, V]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = expEntry[K, V]{}
+	*h = old[:len(old)-1]
+	e.i.idx = -1
+	return e
+}
+
+// Update places the item after its expire time changed.
+func (h *expHeap[K, V]) Update(key K, i *item[V]) {
+	switch {
+	case i.Deadline().IsZero():
+		h.Remove(i)
+	case i.idx < 0:
+		heap.Push(h, expEntry[K, V]{key: key, i: i})
+	default:
+		heap.Fix(h, i.idx)
+	}
+}
+
+// Remove drops the item if it is in the heap.
+func (h *expHeap[K, V]) Remove(i *item[V]) {
+	if i.idx >= 0 {
+		heap.Remove(h, i.idx)
+	}
+}
+
+// Due returns the key of the earliest item expired before now.
+func (h expHeap[K, V]) Due(now time.Time) (key K, ok bool) {
+	if len(h) == 0 || !h[0].i.Deadline().Before(now) {
+		return key, false
+	}
+	return h[0].key, true
+}
@@ -4,23 +4,54 @@ import (
 	"time"
 )
 
-type item struct {
-	val  any
-	exp  time.Time
-	hand ExpireFun
+type meta struct {
+	exp   time.Time
+	slide time.Duration // renew exp on access, 0 disables it
+	dead  time.Time     // hard expire time, zero disables it
+	hand  any
+	tags  []string
+	cost  int64
+	size  int64 // estimated bytes of key-value, set by the shard
+	idx   int   // index in the shard expire heap, -1 if absent
 }
 
-func (i *item) IsExpired() bool {
-	return !i.exp.IsZero() && i.exp.Before(time.Now())
+type item[V any] struct {
+	val V
+	meta
 }
 
-func newItem(cnf *options, _ string, val any, opts ...ItemOption) *item {
-	i := &item{val: val}
+// Deadline returns the earlier of exp and dead, zero means never.
+func (m *meta) Deadline() time.Time {
+	if m.dead.IsZero() || (!m.exp.IsZero() && m.exp.Before(m.dead)) {
+		return m.exp
+	}
+	return m.dead
+}
+
+func (i *item[V]) IsExpired() bool {
+	d := i.Deadline()
+	return !d.IsZero() && d.Before(time.Now())
+}
+
+func newItem[K comparable, V any](cnf *options, _ K, val V, opts ...ItemOption) *item[V] {
+	i := &item[V]{val: val}
+	i.cost = 1
+	i.idx = -1
 	if cnf.expire != nil {
 		i.hand = cnf.expire
 	}
 	for _, opt := range opts {
-		opt(i)
+		opt(&i.meta)
+	}
+	return i
+}
+
+// renewItem returns a copy of old holding val, opts are applied on top of its options.
+func renewItem[V any](old *item[V], val V, opts ...ItemOption) *item[V] {
+	i := &item[V]{val: val, meta: old.meta}
+	i.idx = -1
+	for _, opt := range opts {
+		opt(&i.meta)
 	}
 	return i
 }
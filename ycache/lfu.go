@@ -0,0 +1,85 @@
+package ycache
+
+import (
+	"container/list"
+)
+
+type lfuBucket struct {
+	freq  uint64
+	items *list.List
+}
+
+type lfuEntry[K comparable] struct {
+	bucket *list.Element // element of lfu.freqs
+	elem   *list.Element // element of lfuBucket.items
+}
+
+type lfu[K comparable] struct {
+	freqs *list.List // *lfuBucket, ascending by freq
+	dict  map[K]*lfuEntry[K]
+}
+
+func (l *lfu[K]) Add(key K) {
+	if _, y := l.dict[key]; y {
+		l.Touch(key)
+		return
+	}
+	front := l.freqs.Front()
+	if front == nil || front.Value.(*lfuBucket).freq != 1 {
+		front = l.freqs.PushFront(&lfuBucket{freq: 1, items: list.New()})
+	}
+	l.dict[key] = &lfuEntry[K]{
+		bucket: front,
+		elem:   front.Value.(*lfuBucket).items.PushFront(key),
+	}
+}
+
+func (l *lfu[K]) Touch(key K) {
+	e, y := l.dict[key]
+	if !y {
+		return
+	}
+	cur := e.bucket.Value.(*lfuBucket)
+	next := e.bucket.Next()
+	if next == nil || next.Value.(*lfuBucket).freq != cur.freq+1 {
+		next = l.freqs.InsertAfter(&lfuBucket{freq: cur.freq + 1, items: list.New()}, e.bucket)
+	}
+	cur.items.Remove(e.elem)
+	if cur.items.Len() == 0 {
+		l.freqs.Remove(e.bucket)
+	}
+	e.bucket = next
+	e.elem = next.Value.(*lfuBucket).items.PushFront(key)
+}
+
+func (l *lfu[K]) Del(key K) {
+	e, y := l.dict[key]
+	if !y {
+		return
+	}
+	cur := e.bucket.Value.(*lfuBucket)
+	cur.items.Remove(e.elem)
+	if cur.items.Len() == 0 {
+		l.freqs.Remove(e.bucket)
+	}
+	delete(l.dict, key)
+}
+
+func (l *lfu[K]) Victim() (key K, ok bool) {
+	if front := l.freqs.Front(); front != nil {
+		return front.Value.(*lfuBucket).items.Back().Value.(K), true
+	}
+	return key, false
+}
+
+func (l *lfu[K]) Admit(_, _ K) bool {
+	return true
+}
+
+// NewLFU returns a least frequently used evictor, ties are broken by recency.
+func NewLFU[K comparable]() Evictor[K] {
+	return &lfu[K]{
+		freqs: list.New(),
+		dict:  make(map[K]*lfuEntry[K]),
+	}
+}
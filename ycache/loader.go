@@ -0,0 +1,126 @@
+package ycache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// loadFun loads the value of a missing key, ttl <= 0 means no expiry.
+type loadFun[V any] func() (val V, ttl time.Duration, err error)
+
+type call[V any] struct {
+	wg  sync.WaitGroup
+	val V
+	err error
+}
+
+type negative struct {
+	err error
+	exp time.Time
+}
+
+// flight coalesces concurrent loads of the same key.
+type flight[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+	neg   map[K]negative
+}
+
+// Negative returns the cached loader error of the key.
+func (f *flight[K, V]) Negative(key K) (err error, has bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, y := f.neg[key]
+	if !y {
+		return nil, false
+	}
+	if n.exp.Before(time.Now()) {
+		delete(f.neg, key)
+		return nil, false
+	}
+	return n.err, true
+}
+
+// Do runs fn once for concurrent callers of the same key,
+// stale marks a background refresh which neither waits nor caches errors.
+func (f *flight[K, V]) Do(c *cache[K, V], key K, fn loadFun[V], stale bool) (val V, err error) {
+	f.mu.Lock()
+	if cl, y := f.calls[key]; y {
+		f.mu.Unlock()
+		if stale {
+			return val, nil
+		}
+		cl.wg.Wait()
+		return cl.val, cl.err
+	}
+	cl := new(call[V])
+	cl.wg.Add(1)
+	f.calls[key] = cl
+	f.mu.Unlock()
+	if stale {
+		go f.run(c, key, fn, cl, stale)
+		return val, nil
+	}
+	f.run(c, key, fn, cl, stale)
+	return cl.val, cl.err
+}
+
+// Check drops the expired negative results.
+func (f *flight[K, V]) Check() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	for k, n := range f.neg {
+		if n.exp.Before(now) {
+			delete(f.neg, k)
+		}
+	}
+}
+
+/*
+  Package private
+*/
+
+func (f *flight[K, V]) run(c *cache[K, V], key K, fn loadFun[V], cl *call[V], stale bool) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			cl.err = fmt.Errorf("ycache: loader panic: %v", rec)
+		}
+		f.mu.Lock()
+		delete(f.calls, key)
+		if cl.err == nil {
+			delete(f.neg, key)
+		} else if stale {
+			c.opts.log.Errorf("ycache: refresh error: %v", cl.err)
+		} else if c.opts.negative > 0 {
+			f.neg[key] = negative{err: cl.err, exp: time.Now().Add(c.opts.negative)}
+		}
+		f.mu.Unlock()
+		cl.wg.Done()
+	}()
+	if !stale {
+		// a flight ending between the miss of the caller and now stored the key
+		if val, _, has := c.getShard(key).Peek(key); has {
+			cl.val = val
+			return
+		}
+	}
+	val, ttl, err := fn()
+	if cl.err = err; err != nil {
+		return
+	}
+	cl.val = val
+	if ttl > 0 {
+		c.Set(key, val, WithItemDur(ttl))
+	} else {
+		c.Set(key, val)
+	}
+}
+
+func newFlight[K comparable, V any]() *flight[K, V] {
+	return &flight[K, V]{
+		calls: make(map[K]*call[V]),
+		neg:   make(map[K]negative),
+	}
+}
@@ -0,0 +1,141 @@
+package ycache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoadCoalesce(t *testing.T) {
+	c := NewTyped[string, int](WithInterval(time.Hour))
+	defer c.Close()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func() (int, time.Duration, error) {
+		calls.Add(1)
+		<-release
+		return 7, time.Hour, nil
+	}
+	const callers = 50
+	var started, done sync.WaitGroup
+	vals := make([]int, callers)
+	errs := make([]error, callers)
+	started.Add(callers)
+	done.Add(callers)
+	for n := 0; n < callers; n++ {
+		go func(n int) {
+			defer done.Done()
+			started.Done()
+			vals[n], errs[n] = c.GetOrLoad(`k`, loader)
+		}(n)
+	}
+	started.Wait()
+	eventually(t, `the loader to start`, func() bool { return calls.Load() > 0 })
+	time.Sleep(20 * time.Millisecond) // lets the other callers join the flight
+	close(release)
+	done.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader ran %d times, want 1", n)
+	}
+	for n := range vals {
+		if vals[n] != 7 || errs[n] != nil {
+			t.Fatalf("caller %d got %d, %v, want 7, nil", n, vals[n], errs[n])
+		}
+	}
+	if ttl, has := c.TTL(`k`); !has || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("TTL(k) = %v, %v, want the loader ttl", ttl, has)
+	}
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	c := NewTyped[string, int](WithInterval(time.Hour))
+	defer c.Close()
+	_, err := c.GetOrLoad(`k`, func() (int, time.Duration, error) {
+		panic(`boom`)
+	})
+	if err == nil {
+		t.Fatal("GetOrLoad() of a panicking loader = nil error")
+	}
+	if c.Has(`k`) {
+		t.Error("Has(k) = true after a panicking loader")
+	}
+}
+
+func TestNegativeTTL(t *testing.T) {
+	errLoad := errors.New(`load failed`)
+	var calls atomic.Int32
+	loader := func() (int, time.Duration, error) {
+		calls.Add(1)
+		return 0, 0, errLoad
+	}
+
+	c := NewTyped[string, int](WithInterval(time.Hour), WithNegativeTTL(50*time.Millisecond))
+	defer c.Close()
+	for n := 0; n < 3; n++ {
+		if _, err := c.GetOrLoad(`k`, loader); !errors.Is(err, errLoad) {
+			t.Fatalf("GetOrLoad() = %v, want %v", err, errLoad)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader ran %d times within the negative ttl, want 1", n)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := c.GetOrLoad(`k`, loader); !errors.Is(err, errLoad) {
+		t.Fatalf("GetOrLoad() = %v, want %v", err, errLoad)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("loader ran %d times after the negative ttl, want 2", n)
+	}
+	if v, err := c.GetOrLoad(`k`, func() (int, time.Duration, error) { return 1, 0, nil }); err == nil {
+		t.Errorf("GetOrLoad() = %d, nil within the negative ttl, want the cached error", v)
+	}
+
+	calls.Store(0)
+	d := NewTyped[string, int](WithInterval(time.Hour))
+	defer d.Close()
+	for n := 0; n < 3; n++ {
+		_, _ = d.GetOrLoad(`k`, loader)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("loader ran %d times without negative ttl, want 3", n)
+	}
+}
+
+func TestRefreshAhead(t *testing.T) {
+	c := NewTyped[string, int](WithInterval(time.Hour), WithRefreshAhead(time.Hour))
+	defer c.Close()
+	c.Set(`k`, 1, WithItemDur(time.Minute))
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func() (int, time.Duration, error) {
+		calls.Add(1)
+		<-release
+		return 2, time.Minute, nil
+	}
+	for n := 0; n < 5; n++ {
+		if v, err := c.GetOrLoad(`k`, loader); v != 1 || err != nil {
+			t.Fatalf("GetOrLoad() = %d, %v during the refresh, want the stale 1, nil", v, err)
+		}
+	}
+	close(release)
+	eventually(t, `the refreshed value`, func() bool {
+		v, _ := c.Get(`k`)
+		return v == 2
+	})
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader ran %d times, want 1", n)
+	}
+
+	// a failed refresh keeps the stale value
+	if v, err := c.GetOrLoad(`k`, func() (int, time.Duration, error) {
+		return 0, 0, errors.New(`refresh failed`)
+	}); v != 2 || err != nil {
+		t.Fatalf("GetOrLoad() = %d, %v, want the stale 2, nil", v, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if v, has := c.Get(`k`); !has || v != 2 {
+		t.Errorf("Get(k) = %d, %v after a failed refresh, want 2, true", v, has)
+	}
+}
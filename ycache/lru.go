@@ -0,0 +1,50 @@
+package ycache
+
+import (
+	"container/list"
+)
+
+type lru[K comparable] struct {
+	ll   *list.List
+	dict map[K]*list.Element
+}
+
+func (l *lru[K]) Add(key K) {
+	if e, y := l.dict[key]; y {
+		l.ll.MoveToFront(e)
+		return
+	}
+	l.dict[key] = l.ll.PushFront(key)
+}
+
+func (l *lru[K]) Touch(key K) {
+	if e, y := l.dict[key]; y {
+		l.ll.MoveToFront(e)
+	}
+}
+
+func (l *lru[K]) Del(key K) {
+	if e, y := l.dict[key]; y {
+		l.ll.Remove(e)
+		delete(l.dict, key)
+	}
+}
+
+func (l *lru[K]) Victim() (key K, ok bool) {
+	if e := l.ll.Back(); e != nil {
+		return e.Value.(K), true
+	}
+	return key, false
+}
+
+func (l *lru[K]) Admit(_, _ K) bool {
+	return true
+}
+
+// NewLRU returns a least recently used evictor.
+func NewLRU[K comparable]() Evictor[K] {
+	return &lru[K]{
+		ll:   list.New(),
+		dict: make(map[K]*list.Element),
+	}
+}
@@ -2,23 +2,52 @@ package ycache
 
 import (
 	"runtime"
+	"slices"
 	"time"
 
 	"github.com/azeroth-sha/y/ylog"
 )
 
-const DefaultInterval = time.Second
+const (
+	DefaultInterval = time.Second
+	DefaultBatch    = 1024
+)
 
 // ExpireFun item expire callback
-type ExpireFun func(key string, val any)
+type ExpireFun = TypedExpireFun[string, any]
+
+// TypedExpireFun generic item expire callback
+type TypedExpireFun[K comparable, V any] func(key K, val V)
+
+// EvictFun item expire or evict callback
+type EvictFun = TypedEvictFun[string, any]
+
+// TypedEvictFun generic item expire or evict callback
+type TypedEvictFun[K comparable, V any] func(key K, val V, reason Reason)
 
 type Option func(opts *options)
 
 type options struct {
-	interval  time.Duration
-	expire    ExpireFun
-	shardSize int
-	log       ylog.Logger
+	interval   time.Duration
+	expire     any
+	shardSize  int
+	batch      int
+	log        ylog.Logger
+	maxItems   int
+	maxCost    int64
+	maxBytes   int64
+	sizer      any
+	evictor    any
+	negative   time.Duration
+	refresh    time.Duration
+	codec      Codec
+	snapPath   string
+	snapEvery  time.Duration
+	closeFun   bool
+	eventSize  int
+	dropPolicy DropPolicy
+	backend    Backend
+	bcast      Broadcaster
 }
 
 // WithInterval set expire check interval
@@ -31,13 +60,147 @@ func WithInterval(d time.Duration) Option {
 	}
 }
 
-// WithExpireFun set default expire callback
-func WithExpireFun(h ExpireFun) Option {
+// WithExpireBatch set the max expired items removed per shard on each interval,
+// the rest are removed on later intervals or on access
+func WithExpireBatch(n int) Option {
+	return func(opts *options) {
+		if n <= 0 {
+			n = DefaultBatch
+		}
+		opts.batch = n
+	}
+}
+
+// WithExpireFun set default expire callback, K and V must match the cache
+func WithExpireFun[K comparable, V any](h TypedExpireFun[K, V]) Option {
+	return func(opts *options) {
+		if h == nil {
+			opts.expire = nil
+			return
+		}
+		opts.expire = h
+	}
+}
+
+// WithEvictFun set default expire callback with the removal reason,
+// it replaces the callback of WithExpireFun
+func WithEvictFun[K comparable, V any](h TypedEvictFun[K, V]) Option {
 	return func(opts *options) {
+		if h == nil {
+			opts.expire = nil
+			return
+		}
 		opts.expire = h
 	}
 }
 
+// WithMaxEntries set the max number of items, enforced per shard
+func WithMaxEntries(n int) Option {
+	return func(opts *options) {
+		opts.maxItems = max(n, 0)
+	}
+}
+
+// WithMaxCost set the max total cost of items, enforced per shard
+func WithMaxCost(n int64) Option {
+	return func(opts *options) {
+		opts.maxCost = max(n, 0)
+	}
+}
+
+// WithMaxBytes set the max estimated memory size of items, enforced per shard
+func WithMaxBytes(n int64) Option {
+	return func(opts *options) {
+		opts.maxBytes = max(n, 0)
+	}
+}
+
+// WithSizer set the value size estimator, V must match the cache, the default is SizeOf
+func WithSizer[V any](f func(val V) int) Option {
+	return func(opts *options) {
+		if f == nil {
+			opts.sizer = nil
+			return
+		}
+		opts.sizer = f
+	}
+}
+
+// WithEvictor set the eviction policy factory, K must match the cache,
+// NewLRU is used if a limit is set without an evictor
+func WithEvictor[K comparable](f func() Evictor[K]) Option {
+	return func(opts *options) {
+		opts.evictor = f
+	}
+}
+
+// WithNegativeTTL set how long GetOrLoad caches loader errors, 0 disables it
+func WithNegativeTTL(d time.Duration) Option {
+	return func(opts *options) {
+		opts.negative = max(d, 0)
+	}
+}
+
+// WithRefreshAhead set the remaining ttl below which GetOrLoad reloads
+// the key in background while still serving the stale value, 0 disables it
+func WithRefreshAhead(d time.Duration) Option {
+	return func(opts *options) {
+		opts.refresh = max(d, 0)
+	}
+}
+
+// WithCodec set the codec of keys and values, the default is GobCodec
+func WithCodec(c Codec) Option {
+	return func(opts *options) {
+		if c == nil {
+			c = GobCodec{}
+		}
+		opts.codec = c
+	}
+}
+
+// WithSnapshot restore the cache from the file on New and save it every d,
+// d <= 0 only restores
+func WithSnapshot(path string, d time.Duration) Option {
+	return func(opts *options) {
+		opts.snapPath = path
+		opts.snapEvery = d
+	}
+}
+
+// WithCloseFun fire the expire callback of the remaining items on Close
+func WithCloseFun(enable bool) Option {
+	return func(opts *options) {
+		opts.closeFun = enable
+	}
+}
+
+// WithEventBuffer set the buffer size of each subscriber and what is dropped when it is full
+func WithEventBuffer(size int, policy DropPolicy) Option {
+	return func(opts *options) {
+		if size <= 0 {
+			size = DefaultEventBuffer
+		}
+		opts.eventSize = size
+		opts.dropPolicy = policy
+	}
+}
+
+// WithBackend set the shared second tier, changes are written through and
+// misses are read through, Clear and DelPrefix only affect the local tier
+func WithBackend(b Backend) Option {
+	return func(opts *options) {
+		opts.backend = b
+	}
+}
+
+// WithBroadcaster set the channel telling other caches to drop changed keys
+func WithBroadcaster(b Broadcaster) Option {
+	return func(opts *options) {
+		opts.bcast = b
+	}
+}
+
 // WithShardSize set shard size
 func WithShardSize(count int) Option {
 	return func(opts *options) {
@@ -59,25 +222,74 @@ func WithLogger(l ylog.Logger) Option {
 }
 
 // ItemOption item option
-type ItemOption func(i *item)
+type ItemOption func(i *meta)
+
+// WithItemFun set item expire callback, K and V must match the cache
+func WithItemFun[K comparable, V any](h TypedExpireFun[K, V]) ItemOption {
+	return func(i *meta) {
+		i.hand = h
+	}
+}
 
-// WithItemFun set item expire callback
-func WithItemFun(h ExpireFun) ItemOption {
-	return func(i *item) {
+// WithItemEvictFun set item expire callback with the removal reason, K and V must match the cache
+func WithItemEvictFun[K comparable, V any](h TypedEvictFun[K, V]) ItemOption {
+	return func(i *meta) {
 		i.hand = h
 	}
 }
 
+// WithItemCost set item cost used by WithMaxCost, the default is 1
+func WithItemCost(n int64) ItemOption {
+	return func(i *meta) {
+		i.cost = max(n, 0)
+	}
+}
+
 // WithItemTime set item expire time
 func WithItemTime(t time.Time) ItemOption {
-	return func(i *item) {
+	return func(i *meta) {
 		i.exp = t
 	}
 }
 
 // WithItemDur set item expire duration
 func WithItemDur(d time.Duration) ItemOption {
-	return func(i *item) {
+	return func(i *meta) {
 		i.exp = time.Now().Add(d)
 	}
 }
+
+// WithItemSliding set item expire duration renewed by each Get or Has
+func WithItemSliding(d time.Duration) ItemOption {
+	return func(i *meta) {
+		if d <= 0 {
+			i.slide = 0
+			return
+		}
+		i.slide = d
+		i.exp = time.Now().Add(d)
+	}
+}
+
+// WithItemTags set item tags, see InvalidateTag and KeysByTag
+func WithItemTags(tags ...string) ItemOption {
+	return func(i *meta) {
+		i.tags = nil
+		for _, tag := range tags {
+			if !slices.Contains(i.tags, tag) {
+				i.tags = append(i.tags, tag)
+			}
+		}
+	}
+}
+
+// WithItemMaxAge set item max lifetime, sliding or Expire can not extend it
+func WithItemMaxAge(d time.Duration) ItemOption {
+	return func(i *meta) {
+		if d <= 0 {
+			i.dead = time.Time{}
+			return
+		}
+		i.dead = time.Now().Add(d)
+	}
+}
@@ -0,0 +1,105 @@
+package ycache
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+)
+
+// orderBlock is the max entries of an order block before it splits.
+const orderBlock = 512
+
+type hashKey[K comparable] struct {
+	h uint32
+	k K
+}
+
+// order keeps the keys of a shard sorted by hash in small blocks, so
+// Scan pages from a hash without sorting the shard and updates only move
+// the entries of one block.
+type order[K comparable] struct {
+	blocks [][]hashKey[K]
+}
+
+// Add inserts the key, it must not be present.
+func (o *order[K]) Add(h uint32, k K) {
+	if len(o.blocks) == 0 {
+		o.blocks = append(o.blocks, append(make([]hashKey[K], 0, orderBlock), hashKey[K]{h: h, k: k}))
+		return
+	}
+	b := min(o.block(h), len(o.blocks)-1)
+	blk := o.blocks[b]
+	i := sort.Search(len(blk), func(i int) bool { return blk[i].h > h })
+	blk = slices.Insert(blk, i, hashKey[K]{h: h, k: k})
+	if len(blk) <= orderBlock {
+		o.blocks[b] = blk
+		return
+	}
+	half := len(blk) / 2
+	tail := append(make([]hashKey[K], 0, orderBlock), blk[half:]...)
+	o.blocks[b] = blk[:half]
+	o.blocks = slices.Insert(o.blocks, b+1, tail)
+}
+
+// Del removes the key if present.
+func (o *order[K]) Del(h uint32, k K) {
+	for b := o.block(h); b < len(o.blocks); b++ {
+		blk := o.blocks[b]
+		i := sort.Search(len(blk), func(i int) bool { return blk[i].h >= h })
+		for ; i < len(blk) && blk[i].h == h; i++ {
+			if blk[i].k == k {
+				if blk = slices.Delete(blk, i, i+1); len(blk) == 0 {
+					o.blocks = slices.Delete(o.blocks, b, b+1)
+				} else {
+					o.blocks[b] = blk
+				}
+				return
+			}
+		}
+		if i < len(blk) {
+			return
+		}
+	}
+}
+
+// Walk calls fn for the keys from hash from in order until it returns false.
+func (o *order[K]) Walk(from uint64, fn func(h uint32, k K) bool) {
+	if from > uint64(^uint32(0)) {
+		return
+	}
+	h := uint32(from)
+	for b := o.block(h); b < len(o.blocks); b++ {
+		blk := o.blocks[b]
+		i := sort.Search(len(blk), func(i int) bool { return blk[i].h >= h })
+		for ; i < len(blk); i++ {
+			if !fn(blk[i].h, blk[i].k) {
+				return
+			}
+		}
+	}
+}
+
+// block returns the first block whose last hash is at least h.
+func (o *order[K]) block(h uint32) int {
+	return sort.Search(len(o.blocks), func(b int) bool {
+		blk := o.blocks[b]
+		return blk[len(blk)-1].h >= h
+	})
+}
+
+func newOrder[K comparable, V any](hash func(K) uint32, dict map[K]*item[V]) *order[K] {
+	all := make([]hashKey[K], 0, len(dict))
+	for k := range dict {
+		all = append(all, hashKey[K]{h: hash(k), k: k})
+	}
+	slices.SortFunc(all, func(a, b hashKey[K]) int {
+		return cmp.Compare(a.h, b.h)
+	})
+	o := new(order[K])
+	for len(all) > 0 {
+		n := min(len(all), orderBlock/2)
+		o.blocks = append(o.blocks, append(make([]hashKey[K], 0, orderBlock), all[:n]...))
+		all = all[n:]
+	}
+	return o
+}
@@ -0,0 +1,89 @@
+package ycache
+
+import (
+	"strings"
+)
+
+const (
+	DefaultScanCount = 10
+
+	// scan cursor: shard index << cursorBits | key hash to resume from
+	cursorBits = 33
+	cursorMask = 1<<cursorBits - 1
+)
+
+func (c *cache[K, V]) Range(fn func(key K, val V) bool) {
+	if c.isClosed() {
+		return
+	}
+	for _, s := range c.bucket {
+		for _, rec := range s.Collect(c.opts, nil) {
+			if !fn(rec.key, rec.val) {
+				return
+			}
+		}
+	}
+}
+
+func (c *cache[K, V]) Scan(cursor uint64, match string, count int) (keys []K, next uint64) {
+	if c.isClosed() {
+		return nil, 0
+	}
+	if count <= 0 {
+		count = DefaultScanCount
+	}
+	filter := c.matcher(match)
+	idx, from := int(cursor>>cursorBits), cursor&cursorMask
+	for idx < len(c.bucket) && count > 0 {
+		var page []K
+		page, from, count = c.bucket[idx].Scan(c.opts, from, count, filter)
+		keys = append(keys, page...)
+		if from == 0 {
+			idx++
+		}
+	}
+	if idx >= len(c.bucket) {
+		return keys, 0
+	}
+	return keys, uint64(idx)<<cursorBits | from
+}
+
+func (c *cache[K, V]) Keys(pattern string) (keys []K) {
+	if c.isClosed() {
+		return nil
+	}
+	filter := c.matcher(pattern)
+	for _, s := range c.bucket {
+		for _, rec := range s.Collect(c.opts, filter) {
+			keys = append(keys, rec.key)
+		}
+	}
+	return keys
+}
+
+func (c *cache[K, V]) DelPrefix(prefix string) int {
+	if c.isClosed() {
+		return 0
+	}
+	count := 0
+	for _, s := range c.bucket {
+		count += s.DelFunc(c.opts, func(k K) bool {
+			return strings.HasPrefix(c.str(k), prefix)
+		})
+	}
+	return count
+}
+
+/*
+  Package private
+*/
+
+// matcher returns nil if every key matches.
+func (c *cache[K, V]) matcher(pattern string) func(K) bool {
+	if pattern == "" || pattern == "*" {
+		return nil
+	}
+	return func(k K) bool {
+		return Match(pattern, c.str(k))
+	}
+}
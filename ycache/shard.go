@@ -5,47 +5,80 @@ import (
 	"time"
 )
 
-type shard struct {
-	mu   *sync.RWMutex
-	dict map[string]*item
+type shard[K comparable, V any] struct {
+	mu       *sync.RWMutex
+	dict     map[K]*item[V]
+	cost     int64
+	bytes    int64
+	maxItems int
+	maxCost  int64
+	maxBytes int64
+	sizer    func(K, V) int64
+	pmu      *sync.Mutex // guards evict.Touch under the read lock
+	evict    Evictor[K]
+	stat     counters
+	exps     expHeap[K, V]
+	tags     map[string]map[K]struct{}
+	hash     func(K) uint32
+	order    *order[K] // keys by hash, built by the first Scan
+	hub      *hub[K, V]
 }
 
-func (s *shard) Has(cnf *options, key string) (has bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	if i, y := s.dict[key]; y {
-		if i.IsExpired() {
-			s.expired(cnf, key)
-			return false
-		}
-		return true
-	}
-	return false
+func (s *shard[K, V]) Has(cnf *options, key K) (has bool) {
+	_, has = s.Get(cnf, key)
+	return has
 }
 
-func (s *shard) Set(cnf *options, key string, val any, opts ...ItemOption) {
+// Set stores the key-value and returns its expire time, also if the item is refused.
+func (s *shard[K, V]) Set(cnf *options, key K, val V, opts ...ItemOption) (exp time.Time) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if i, y := s.dict[key]; y && i.IsExpired() {
 		s.expired(cnf, key)
 	}
-	s.dict[key] = newItem(cnf, key, val, opts...)
+	i := newItem(cnf, key, val, opts...)
+	s.insert(cnf, key, i)
+	return i.Deadline()
 }
 
-func (s *shard) Get(cnf *options, key string) (val any, has bool) {
+func (s *shard[K, V]) Get(cnf *options, key K) (val V, has bool) {
+	val, _, has = s.GetExp(cnf, key)
+	return val, has
+}
+
+func (s *shard[K, V]) GetExp(cnf *options, key K) (val V, exp time.Time, has bool) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
-	if i, y := s.dict[key]; y {
-		if i.IsExpired() {
-			s.expired(cnf, key)
-			return nil, false
+	i, y := s.dict[key]
+	expired := y && i.IsExpired()
+	slide := false
+	if y && !expired {
+		val, exp, has = i.val, i.Deadline(), true
+		slide = i.slide > 0
+		if s.evict != nil {
+			s.touch(key)
 		}
-		return i.val, true
 	}
-	return nil, false
+	s.mu.RUnlock()
+	if expired {
+		s.DelExpired(cnf, key)
+	} else if slide {
+		exp, has = s.slide(cnf, key)
+	}
+	s.stat.Lookup(has)
+	return val, exp, has
+}
+
+// Peek returns the live item without side effects.
+func (s *shard[K, V]) Peek(key K) (val V, exp time.Time, has bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if i, y := s.dict[key]; y && !i.IsExpired() {
+		return i.val, i.Deadline(), true
+	}
+	return val, exp, false
 }
 
-func (s *shard) Del(cnf *options, key string) (has bool) {
+func (s *shard[K, V]) Del(cnf *options, key K) (has bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if i, y := s.dict[key]; y {
@@ -53,61 +86,93 @@ func (s *shard) Del(cnf *options, key string) (has bool) {
 			s.expired(cnf, key)
 			return false
 		} else {
-			delete(s.dict, key)
+			s.deleted(key)
 			return true
 		}
 	}
 	return false
 }
 
-func (s *shard) GetDel(cnf *options, key string) (val any, has bool) {
+func (s *shard[K, V]) GetDel(cnf *options, key K) (val V, has bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer func() { s.stat.Lookup(has) }()
 	if i, y := s.dict[key]; y {
 		if i.IsExpired() {
 			s.expired(cnf, key)
-			return nil, false
+			return val, false
 		} else {
-			delete(s.dict, key)
+			s.deleted(key)
 			return i.val, true
 		}
 	}
-	return nil, false
+	return val, false
 }
 
-func (s *shard) GetSet(cnf *options, key string, newVal any, opts ...ItemOption) (oldVal any, has bool) {
+func (s *shard[K, V]) GetSet(cnf *options, key K, newVal V, opts ...ItemOption) (oldVal V, exp time.Time, has bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer func() { s.stat.Lookup(has) }()
 	if i, y := s.dict[key]; y {
 		if i.IsExpired() {
 			s.expired(cnf, key)
-			return nil, false
+			return oldVal, exp, false
 		} else {
 			oldVal = i.val
-			s.dict[key] = newItem(cnf, key, newVal, opts...)
-			return oldVal, true
+			i = newItem(cnf, key, newVal, opts...)
+			s.insert(cnf, key, i)
+			return oldVal, i.Deadline(), true
 		}
 	} else {
-		s.dict[key] = newItem(cnf, key, newVal, opts...)
-		return nil, false
+		i = newItem(cnf, key, newVal, opts...)
+		s.insert(cnf, key, i)
+		return oldVal, i.Deadline(), false
 	}
 }
 
-func (s *shard) SetX(cnf *options, key string, val any, opts ...ItemOption) (ok bool) {
+func (s *shard[K, V]) SetX(cnf *options, key K, val V, opts ...ItemOption) (exp time.Time, ok bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if i, y := s.dict[key]; y && i.IsExpired() {
 		s.expired(cnf, key)
 	}
 	if _, y := s.dict[key]; y {
-		return false
+		return exp, false
 	} else {
-		s.dict[key] = newItem(cnf, key, val, opts...)
-		return true
+		i := newItem(cnf, key, val, opts...)
+		return i.Deadline(), s.insert(cnf, key, i)
+	}
+}
+
+// Update stores the value returned by fn if it also returns true, an existing
+// item keeps its options unless opts are given.
+func (s *shard[K, V]) Update(cnf *options, key K, fn func(old V, has bool) (V, bool), opts ...ItemOption) (val V, exp time.Time, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, has := s.dict[key]
+	if has && i.IsExpired() {
+		s.expired(cnf, key)
+		has = false
+	}
+	if has {
+		val = i.val
 	}
+	newVal, store := fn(val, has)
+	if !store {
+		return val, exp, false
+	}
+	if has {
+		i = renewItem(i, newVal, opts...)
+	} else {
+		i = newItem(cnf, key, newVal, opts...)
+	}
+	if ok = s.insert(cnf, key, i); ok {
+		val, exp = newVal, i.Deadline()
+	}
+	return val, exp, ok
 }
 
-func (s *shard) DelExpired(cnf *options, key string) (has, exp bool) {
+func (s *shard[K, V]) DelExpired(cnf *options, key K) (has, exp bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if i, y := s.dict[key]; y {
@@ -119,74 +184,219 @@ func (s *shard) DelExpired(cnf *options, key string) (has, exp bool) {
 	return has, exp
 }
 
-func (s *shard) All(cnf *options) (kvs map[string]any) {
+func (s *shard[K, V]) All(cnf *options) (kvs map[K]V) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
-	kvs = make(map[string]any)
+	kvs = make(map[K]V)
+	var exps []K
 	for k, v := range s.dict {
 		if v.IsExpired() {
-			s.expired(cnf, k)
+			exps = append(exps, k)
 		} else {
 			kvs[k] = v.val
 		}
 	}
+	s.mu.RUnlock()
+	for _, k := range exps {
+		s.DelExpired(cnf, k)
+	}
 	return kvs
 }
 
-func (s *shard) Len(_ *options) int {
+// Collect returns the live items accepted by filter, nil accepts all.
+func (s *shard[K, V]) Collect(cnf *options, filter func(K) bool) (recs []record[K, V]) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.dict)
+	var exps []K
+	for k, v := range s.dict {
+		if filter != nil && !filter(k) {
+			continue
+		}
+		if v.IsExpired() {
+			exps = append(exps, k)
+		} else {
+			recs = append(recs, record[K, V]{key: k, val: v.val, exp: v.exp, slide: v.slide, dead: v.dead, tags: v.tags})
+		}
+	}
+	s.mu.RUnlock()
+	for _, k := range exps {
+		s.DelExpired(cnf, k)
+	}
+	return recs
+}
+
+// Scan returns up to count live keys ordered by hash from the given one,
+// keys sharing the last hash are all included. It returns the hash to resume
+// from, 0 if the shard is done, and the remaining count. The first call
+// builds the hash order of the shard, later changes keep it.
+func (s *shard[K, V]) Scan(cnf *options, from uint64, count int, filter func(K) bool) (keys []K, next uint64, rest int) {
+	var exps []K
+	n, last := 0, uint32(0)
+	s.mu.RLock()
+	if s.order == nil {
+		s.mu.RUnlock()
+		s.mu.Lock()
+		if s.order == nil {
+			s.order = newOrder(s.hash, s.dict)
+		}
+		s.mu.Unlock()
+		s.mu.RLock()
+	}
+	s.order.Walk(from, func(h uint32, k K) bool {
+		if n >= count && h != last {
+			next = uint64(last) + 1
+			return false
+		}
+		if s.dict[k].IsExpired() {
+			exps = append(exps, k)
+			return true
+		}
+		n, last = n+1, h
+		if filter == nil || filter(k) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	s.mu.RUnlock()
+	for _, k := range exps {
+		s.DelExpired(cnf, k)
+	}
+	return keys, next, max(count-n, 0)
 }
 
-func (s *shard) Clear(cnf *options) int {
+// DelFunc deletes the keys accepted by filter.
+func (s *shard[K, V]) DelFunc(cnf *options, filter func(K) bool) int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	count := 0
 	for k, v := range s.dict {
+		if !filter(k) {
+			continue
+		}
 		if v.IsExpired() {
 			s.expired(cnf, k)
 		} else {
-			delete(s.dict, k)
+			s.deleted(k)
 			count++
 		}
 	}
 	return count
 }
 
-func (s *shard) TTL(cnf *options, key string) (ttl time.Duration, has bool) {
+// Bytes returns the estimated memory size of the items.
+func (s *shard[K, V]) Bytes(_ *options) int64 {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	if i, y := s.dict[key]; y {
-		if i.IsExpired() {
-			s.expired(cnf, key)
-			return 0, false
+	return s.bytes
+}
+
+func (s *shard[K, V]) Len(_ *options) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.dict)
+}
+
+func (s *shard[K, V]) Clear(cnf *options) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for k, v := range s.dict {
+		if v.IsExpired() {
+			s.expired(cnf, k)
+		} else {
+			s.deleted(k)
+			count++
 		}
-		return i.exp.Sub(time.Now()), true
 	}
-	return 0, false
+	return count
+}
+
+func (s *shard[K, V]) TTL(cnf *options, key K) (ttl time.Duration, has bool) {
+	s.mu.RLock()
+	i, y := s.dict[key]
+	exp := y && i.IsExpired()
+	if y && !exp {
+		ttl, has = i.Deadline().Sub(time.Now()), true
+	}
+	s.mu.RUnlock()
+	if exp {
+		s.DelExpired(cnf, key)
+	}
+	return ttl, has
 }
 
-func (s *shard) Expire(cnf *options, key string, ttl time.Duration) (ok bool) {
+// Expire sets the expire time of the key and returns its value and deadline.
+func (s *shard[K, V]) Expire(cnf *options, key K, ttl time.Duration) (val V, exp time.Time, ok bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if i, y := s.dict[key]; y {
 		if i.IsExpired() {
 			s.expired(cnf, key)
-			return false
+			return val, exp, false
 		}
 		i.exp = time.Now().Add(ttl)
-		return true
+		s.exps.Update(key, i)
+		return i.val, i.Deadline(), true
 	}
-	return false
+	return val, exp, false
+}
+
+// TagKeys returns the live keys carrying the tag.
+func (s *shard[K, V]) TagKeys(cnf *options, tag string) (keys []K) {
+	s.mu.RLock()
+	var exps []K
+	for k := range s.tags[tag] {
+		if s.dict[k].IsExpired() {
+			exps = append(exps, k)
+		} else {
+			keys = append(keys, k)
+		}
+	}
+	s.mu.RUnlock()
+	for _, k := range exps {
+		s.DelExpired(cnf, k)
+	}
+	return keys
 }
 
-func (s *shard) Check(cnf *options) {
+// DelTag deletes the keys carrying the tag and returns the live ones.
+func (s *shard[K, V]) DelTag(cnf *options, tag string) (keys []K) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	for k, v := range s.dict {
-		if v.IsExpired() {
+	for k := range s.tags[tag] {
+		if s.dict[k].IsExpired() {
 			s.expired(cnf, k)
+		} else {
+			s.deleted(k)
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Check removes at most limit expired items, it returns false if more are due.
+func (s *shard[K, V]) Check(cnf *options, limit int) (done bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for n := 0; n < limit; n++ {
+		key, ok := s.exps.Due(now)
+		if !ok {
+			return true
+		}
+		s.expired(cnf, key)
+	}
+	_, ok := s.exps.Due(now)
+	return !ok
+}
+
+// Flush removes all items, firing their callbacks with ReasonClosed if fire is true.
+func (s *shard[K, V]) Flush(cnf *options, fire bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k := range s.dict {
+		if fire {
+			s.removed(cnf, k, ReasonClosed)
+		} else {
+			s.unlink(k)
 		}
 	}
 }
@@ -195,22 +405,188 @@ func (s *shard) Check(cnf *options) {
   Package private
 */
 
-func (s *shard) expired(cnf *options, key string) {
-	i := s.dict[key]
-	delete(s.dict, key)
+func (s *shard[K, V]) expired(cnf *options, key K) {
+	s.removed(cnf, key, ReasonExpired)
+}
+
+func (s *shard[K, V]) removed(cnf *options, key K, reason Reason) {
+	i := s.unlink(key)
+	s.stat.Removed(reason)
+	switch reason {
+	case ReasonExpired:
+		s.hub.Publish(OpExpire, key, i.val)
+	case ReasonEvicted:
+		s.hub.Publish(OpEvict, key, i.val)
+	}
 	defer func() {
 		if rec := recover(); rec != nil {
-			cnf.log.Errorf("ycache: expired panic: %v", rec)
+			cnf.log.Errorf("ycache: %s panic: %v", reason, rec)
 		}
 	}()
-	if i.hand != nil {
-		i.hand(key, i.val)
+	switch h := i.hand.(type) {
+	case TypedExpireFun[K, V]:
+		if h != nil {
+			h(key, i.val)
+		}
+	case TypedEvictFun[K, V]:
+		if h != nil {
+			h(key, i.val, reason)
+		}
+	case nil:
+	default:
+		cnf.log.Errorf("ycache: item callback type mismatch: %T", h)
+	}
+}
+
+// insert stores the item and evicts victims over the shard limits,
+// it returns false if the item is refused. A refused item evicts the
+// existing one of the key so the replaced value is not served.
+func (s *shard[K, V]) insert(cnf *options, key K, i *item[V]) bool {
+	old, y := s.dict[key]
+	i.size = s.sizer(key, i.val)
+	if s.evict != nil {
+		if (s.maxCost > 0 && i.cost > s.maxCost) || (s.maxBytes > 0 && i.size > s.maxBytes) {
+			if y {
+				s.removed(cnf, key, ReasonEvicted)
+			}
+			return false
+		}
+		n, cost, size := 1, i.cost, i.size
+		if y {
+			n, cost, size = 0, i.cost-old.cost, i.size-old.size
+			s.evict.Touch(key)
+		}
+		for first := true; s.over(n, cost, size); first = false {
+			victim, ok := s.evict.Victim()
+			if !ok || victim == key {
+				break
+			}
+			if first && !y && !s.evict.Admit(key, victim) {
+				return false
+			}
+			s.removed(cnf, victim, ReasonEvicted)
+		}
+		if !y {
+			s.evict.Add(key)
+		}
+	}
+	if y {
+		s.cost -= old.cost
+		s.bytes -= old.size
+		s.exps.Remove(old)
+		s.untag(key, old)
+	}
+	s.dict[key] = i
+	if !y && s.order != nil {
+		s.order.Add(s.hash(key), key)
+	}
+	s.tag(key, i)
+	s.cost += i.cost
+	s.bytes += i.size
+	s.exps.Update(key, i)
+	s.stat.sets.Add(1)
+	if y {
+		s.hub.Publish(OpOverwrite, key, i.val)
+	} else {
+		s.hub.Publish(OpSet, key, i.val)
+	}
+	return true
+}
+
+// slide renews the sliding expire time of the key, capped by its hard one.
+func (s *shard[K, V]) slide(cnf *options, key K) (exp time.Time, has bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, y := s.dict[key]
+	if !y {
+		return exp, false
+	}
+	if i.IsExpired() {
+		s.expired(cnf, key)
+		return exp, false
+	}
+	i.exp = time.Now().Add(i.slide)
+	s.exps.Update(key, i)
+	return i.Deadline(), true
+}
+
+// deleted removes the key on request of the caller.
+func (s *shard[K, V]) deleted(key K) {
+	i := s.unlink(key)
+	s.stat.deletes.Add(1)
+	s.hub.Publish(OpDelete, key, i.val)
+}
+
+// unlink removes the key without callbacks.
+func (s *shard[K, V]) unlink(key K) *item[V] {
+	i := s.dict[key]
+	delete(s.dict, key)
+	if s.order != nil {
+		s.order.Del(s.hash(key), key)
+	}
+	s.cost -= i.cost
+	s.bytes -= i.size
+	s.exps.Remove(i)
+	s.untag(key, i)
+	if s.evict != nil {
+		s.evict.Del(key)
+	}
+	return i
+}
+
+func (s *shard[K, V]) tag(key K, i *item[V]) {
+	for _, tag := range i.tags {
+		keys, y := s.tags[tag]
+		if !y {
+			keys = make(map[K]struct{})
+			s.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+func (s *shard[K, V]) untag(key K, i *item[V]) {
+	for _, tag := range i.tags {
+		if keys, y := s.tags[tag]; y {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(s.tags, tag)
+			}
+		}
+	}
+}
+
+func (s *shard[K, V]) touch(key K) {
+	s.pmu.Lock()
+	defer s.pmu.Unlock()
+	s.evict.Touch(key)
+}
+
+func (s *shard[K, V]) over(n int, cost, size int64) bool {
+	return (s.maxItems > 0 && len(s.dict)+n > s.maxItems) ||
+		(s.maxCost > 0 && s.cost+cost > s.maxCost) ||
+		(s.maxBytes > 0 && s.bytes+size > s.maxBytes)
+}
+
+func newShard[K comparable, V any](cnf *options, evict Evictor[K], hub *hub[K, V], sizer func(K, V) int64, hash func(K) uint32) *shard[K, V] {
+	return &shard[K, V]{
+		mu:       new(sync.RWMutex),
+		dict:     make(map[K]*item[V]),
+		tags:     make(map[string]map[K]struct{}),
+		maxItems: perShard(cnf.maxItems, cnf.shardSize),
+		maxCost:  perShard(cnf.maxCost, int64(cnf.shardSize)),
+		maxBytes: perShard(cnf.maxBytes, int64(cnf.shardSize)),
+		sizer:    sizer,
+		hash:     hash,
+		pmu:      new(sync.Mutex),
+		evict:    evict,
+		hub:      hub,
 	}
 }
 
-func newShard() *shard {
-	return &shard{
-		mu:   new(sync.RWMutex),
-		dict: make(map[string]*item),
+func perShard[T int | int64](total, n T) T {
+	if total <= 0 {
+		return 0
 	}
+	return (total + n - 1) / n
 }
@@ -0,0 +1,122 @@
+package ycache
+
+import (
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+)
+
+const benchEntries = 1_000_000
+
+var (
+	benchOnce   sync.Once
+	benchHandle TypedCache[int, int] // keeps the finalizer from closing the cache
+	benchCache  *cache[int, int]
+)
+
+// benchFill returns a cache holding benchEntries keys expiring in an hour,
+// shared by the benchmarks which only overwrite them.
+func benchFill(b *testing.B) *cache[int, int] {
+	b.Helper()
+	benchOnce.Do(func() {
+		benchHandle = NewTyped[int, int](WithInterval(time.Hour))
+		c := benchHandle.(*handle[int, int]).cache
+		for i := 0; i < benchEntries; i++ {
+			c.Set(i, i, WithItemDur(time.Hour))
+		}
+		benchCache = c
+	})
+	b.ResetTimer()
+	return benchCache
+}
+
+// scanAll is the expiry check without the heap, it visits every item.
+func scanAll(c *cache[int, int]) {
+	for _, s := range c.bucket {
+		s.mu.Lock()
+		for k, i := range s.dict {
+			if i.IsExpired() {
+				s.expired(c.opts, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func heapAll(c *cache[int, int]) {
+	c.checkAll()
+}
+
+var checkers = []struct {
+	name  string
+	check func(c *cache[int, int])
+}{
+	{`heap`, heapAll},
+	{`scan`, scanAll},
+}
+
+func BenchmarkCheck(b *testing.B) {
+	for _, ck := range checkers {
+		b.Run(ck.name, func(b *testing.B) {
+			c := benchFill(b)
+			for i := 0; i < b.N; i++ {
+				ck.check(c)
+			}
+		})
+	}
+}
+
+func BenchmarkGetDuringCheck(b *testing.B) {
+	for _, ck := range checkers {
+		b.Run(ck.name, func(b *testing.B) {
+			c := benchFill(b)
+			benchDuring(b, c, ck.check, func(k int) {
+				c.Get(k)
+			})
+		})
+	}
+}
+
+func BenchmarkSetDuringCheck(b *testing.B) {
+	for _, ck := range checkers {
+		b.Run(ck.name, func(b *testing.B) {
+			c := benchFill(b)
+			benchDuring(b, c, ck.check, func(k int) {
+				c.Set(k, k, WithItemDur(time.Hour))
+			})
+		})
+	}
+}
+
+// benchDuring runs op on random keys while check runs each DefaultInterval and
+// reports the slowest op, which waits for a check holding the shard lock.
+func benchDuring(b *testing.B, c *cache[int, int], check func(c *cache[int, int]), op func(k int)) {
+	done := make(chan struct{})
+	wait := new(sync.WaitGroup)
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		tk := time.NewTicker(DefaultInterval)
+		defer tk.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-tk.C:
+				check(c)
+			}
+		}
+	}()
+	var slowest time.Duration
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		op(rand.IntN(benchEntries))
+		slowest = max(slowest, time.Since(start))
+	}
+	b.StopTimer()
+	close(done)
+	wait.Wait()
+	b.ReportMetric(float64(slowest.Nanoseconds()), `max-ns/op`)
+}
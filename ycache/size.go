@@ -0,0 +1,47 @@
+package ycache
+
+import (
+	"reflect"
+)
+
+// Sized is implemented by values reporting their own memory size.
+type Sized interface {
+	Size() int
+}
+
+// SizeOf estimates the memory size of v in bytes, it is the length of
+// strings and byte slices, Size() of Sized values and the size of the
+// type otherwise.
+func SizeOf(v any) int {
+	switch vv := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(vv)
+	case []byte:
+		return len(vv)
+	case Sized:
+		return vv.Size()
+	default:
+		return int(reflect.TypeOf(v).Size())
+	}
+}
+
+/*
+  Package private
+*/
+
+// sizer returns the size function of key-value, V must match WithSizer.
+func (c *cache[K, V]) sizer() func(K, V) int64 {
+	valSize := func(v V) int {
+		return SizeOf(any(v))
+	}
+	if f, ok := c.opts.sizer.(func(V) int); ok {
+		valSize = f
+	} else if c.opts.sizer != nil {
+		c.opts.log.Errorf("ycache: sizer type mismatch: %T", c.opts.sizer)
+	}
+	return func(k K, v V) int64 {
+		return int64(SizeOf(any(k)) + valSize(v))
+	}
+}
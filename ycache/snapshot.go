@@ -0,0 +1,295 @@
+package ycache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/azeroth-sha/y/yfile"
+	"github.com/azeroth-sha/y/ysum"
+)
+
+var (
+	ErrSnapshotFormat  = errors.New("ycache: invalid snapshot")
+	ErrSnapshotVersion = errors.New("ycache: unsupported snapshot version")
+	ErrSnapshotCorrupt = errors.New("ycache: corrupt snapshot record")
+)
+
+const (
+	snapshotMagic   = "YCACHE"
+	snapshotVersion = 3
+	snapshotMaxSize = 256 << 20 // max record payload, larger sizes are corrupt
+)
+
+/*
+  Snapshot format v3, integers are big endian:
+
+    header: magic "YCACHE" | version uint8
+    record: size uint32 | payload [size]byte | crc16(payload) uint16
+    payload: expire unix nano int64, 0 = never | sliding nano int64 |
+             max age unix nano int64, 0 = never |
+             tag count uvarint | (tag size uvarint | tag)... |
+             key size uvarint | key | value
+
+  The stream ends with a zero size record. Older payloads are still
+  restored, version 1 lacks the sliding, max age and tag fields and
+  version 2 the tag fields.
+*/
+
+type record[K comparable, V any] struct {
+	key   K
+	val   V
+	exp   time.Time
+	slide time.Duration
+	dead  time.Time
+	tags  []string
+}
+
+func (c *cache[K, V]) Snapshot(w io.Writer) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	return c.snapshot(w)
+}
+
+func (c *cache[K, V]) Restore(r io.Reader) error {
+	if c.isClosed() {
+		return ErrClosed
+	}
+	br := bufio.NewReader(r)
+	head := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(br, head); err != nil || string(head[:len(snapshotMagic)]) != snapshotMagic {
+		return ErrSnapshotFormat
+	}
+	version := head[len(snapshotMagic)]
+	if version < 1 || version > snapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, version)
+	}
+	for n := 0; ; n++ {
+		payload, err := readRecord(br)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		} else if payload == nil {
+			return nil
+		}
+		rec, err := c.decodeRecord(payload, version)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+		m := meta{exp: rec.exp, slide: rec.slide, dead: rec.dead}
+		if exp := m.Deadline(); exp.IsZero() || exp.After(time.Now()) {
+			c.getShard(rec.key).Set(c.opts, rec.key, rec.val, rec.restore)
+		}
+	}
+}
+
+/*
+  Package private
+*/
+
+// snapshot writes the snapshot without the closed check, Close saves the last one.
+func (c *cache[K, V]) snapshot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	_ = bw.WriteByte(snapshotVersion)
+	buf := new(bytes.Buffer)
+	for _, s := range c.bucket {
+		for _, rec := range s.Collect(c.opts, nil) {
+			buf.Reset()
+			if err := c.encodeRecord(buf, rec); err != nil {
+				return err
+			}
+			if err := writeRecord(bw, buf.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	if err := binary.Write(bw, binary.BigEndian, uint32(0)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// restore is the item option setting the saved expire times.
+func (rec record[K, V]) restore(i *meta) {
+	i.exp = rec.exp
+	i.slide = rec.slide
+	i.dead = rec.dead
+	i.tags = rec.tags
+}
+
+func (c *cache[K, V]) encodeRecord(buf *bytes.Buffer, rec record[K, V]) error {
+	key, err := c.opts.codec.Marshal(&rec.key)
+	if err != nil {
+		return err
+	}
+	val, err := c.opts.codec.Marshal(&rec.val)
+	if err != nil {
+		return err
+	}
+	_ = binary.Write(buf, binary.BigEndian, unixNano(rec.exp))
+	_ = binary.Write(buf, binary.BigEndian, int64(rec.slide))
+	_ = binary.Write(buf, binary.BigEndian, unixNano(rec.dead))
+	buf.Write(binary.AppendUvarint(nil, uint64(len(rec.tags))))
+	for _, tag := range rec.tags {
+		buf.Write(binary.AppendUvarint(nil, uint64(len(tag))))
+		buf.WriteString(tag)
+	}
+	buf.Write(binary.AppendUvarint(nil, uint64(len(key))))
+	buf.Write(key)
+	buf.Write(val)
+	return nil
+}
+
+func (c *cache[K, V]) decodeRecord(payload []byte, version uint8) (rec record[K, V], err error) {
+	head := 8
+	if version >= 2 {
+		head = 24
+	}
+	if len(payload) < head {
+		return rec, ErrSnapshotCorrupt
+	}
+	rec.exp = fromUnixNano(int64(binary.BigEndian.Uint64(payload)))
+	if version >= 2 {
+		rec.slide = time.Duration(binary.BigEndian.Uint64(payload[8:]))
+		rec.dead = fromUnixNano(int64(binary.BigEndian.Uint64(payload[16:])))
+	}
+	payload = payload[head:]
+	if version >= 3 {
+		count, n := binary.Uvarint(payload)
+		if n <= 0 || count > uint64(len(payload)) {
+			return rec, ErrSnapshotCorrupt
+		}
+		payload = payload[n:]
+		for ; count > 0; count-- {
+			var tag []byte
+			if tag, payload, err = readField(payload); err != nil {
+				return rec, err
+			}
+			rec.tags = append(rec.tags, string(tag))
+		}
+	}
+	key, val, err := readField(payload)
+	if err != nil {
+		return rec, err
+	}
+	if err = c.opts.codec.Unmarshal(key, &rec.key); err != nil {
+		return rec, err
+	}
+	err = c.opts.codec.Unmarshal(val, &rec.val)
+	return rec, err
+}
+
+// readField splits a uvarint size prefixed field from b.
+func readField(b []byte) (field, rest []byte, err error) {
+	size, n := binary.Uvarint(b)
+	if n <= 0 || uint64(len(b)-n) < size {
+		return nil, nil, ErrSnapshotCorrupt
+	}
+	return b[n : n+int(size)], b[n+int(size):], nil
+}
+
+// unixNano returns 0 for the zero time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+func writeRecord(w io.Writer, payload []byte) error {
+	if err := binary.Write(w, binary.BigEndian, uint32(len(payload))); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, ysum.Sum16(ysum.NewCrc16(), payload))
+}
+
+// readRecord returns nil at the end of the stream.
+func readRecord(r io.Reader) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	} else if size == 0 {
+		return nil, nil
+	}
+	if size > snapshotMaxSize {
+		return nil, ErrSnapshotCorrupt
+	}
+	// grow with the input so a corrupt size can not allocate more than it holds
+	buf := bytes.NewBuffer(make([]byte, 0, min(int(size)+2, bytes.MinRead)))
+	if _, err := io.CopyN(buf, r, int64(size)+2); err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	payload := buf.Bytes()
+	sum := binary.BigEndian.Uint16(payload[size:])
+	if ysum.Sum16(ysum.NewCrc16(), payload[:size]) != sum {
+		return nil, ErrSnapshotCorrupt
+	}
+	return payload[:size], nil
+}
+
+// saveFile writes the snapshot to a temporary file and renames it.
+func (c *cache[K, V]) saveFile(name string) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err = c.snapshot(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+func (c *cache[K, V]) loadFile(name string) error {
+	if !yfile.IsExist(name) {
+		return nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Restore(f)
+}
+
+func (c *cache[K, V]) snapshotter() {
+	defer c.wait.Done()
+	tk := time.NewTicker(c.opts.snapEvery)
+	defer tk.Stop()
+EXIT:
+	for {
+		select {
+		case <-c.closed:
+			break EXIT
+		case <-tk.C:
+			if err := c.saveFile(c.opts.snapPath); err != nil {
+				c.opts.log.Errorf("ycache: snapshot error: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package ycache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	c := NewTyped[string, int](WithInterval(time.Hour))
+	defer c.Close()
+	c.Set(`a`, 1)
+	c.Set(`b`, 2, WithItemDur(time.Hour))
+	c.Set(`c`, 3, WithItemDur(time.Nanosecond))
+	time.Sleep(time.Millisecond)
+	buf := new(bytes.Buffer)
+	if err := c.Snapshot(buf); err != nil {
+		t.Fatal(err)
+	}
+	r := NewTyped[string, int](WithInterval(time.Hour))
+	defer r.Close()
+	if err := r.Restore(buf); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := r.Get(`a`); v != 1 {
+		t.Errorf("a = %d, want 1", v)
+	}
+	if ttl, has := r.TTL(`b`); !has || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("b ttl = %v, %v", ttl, has)
+	}
+	if r.Has(`c`) {
+		t.Error("expired key c restored")
+	}
+}
+
+func TestSnapshotSliding(t *testing.T) {
+	c := NewTyped[string, int](WithInterval(time.Hour))
+	defer c.Close()
+	c.Set(`s`, 1, WithItemSliding(time.Minute), WithItemMaxAge(time.Hour))
+	buf := new(bytes.Buffer)
+	if err := c.Snapshot(buf); err != nil {
+		t.Fatal(err)
+	}
+	r := NewTyped[string, int](WithInterval(time.Hour))
+	defer r.Close()
+	if err := r.Restore(buf); err != nil {
+		t.Fatal(err)
+	}
+	want := c.(*handle[string, int]).getShard(`s`).dict[`s`]
+	s := r.(*handle[string, int]).getShard(`s`)
+	got := s.dict[`s`]
+	if got == nil || got.slide != time.Minute || !got.dead.Equal(want.dead) || !got.exp.Equal(want.exp) {
+		t.Fatalf("restored item %+v, want %+v", got, want)
+	}
+	if _, has := r.Get(`s`); !has {
+		t.Fatal("Get(s) = false")
+	}
+	if !s.dict[`s`].exp.After(want.exp) {
+		t.Error("Get(s) did not slide the restored item")
+	}
+}
+
+func TestSnapshotTags(t *testing.T) {
+	c := NewTyped[string, int](WithInterval(time.Hour))
+	defer c.Close()
+	c.Set(`a`, 1, WithItemTags(`tenant:1`, `user`))
+	c.Set(`b`, 2, WithItemTags(`tenant:2`))
+	c.Set(`c`, 3)
+	buf := new(bytes.Buffer)
+	if err := c.Snapshot(buf); err != nil {
+		t.Fatal(err)
+	}
+	r := NewTyped[string, int](WithInterval(time.Hour))
+	defer r.Close()
+	if err := r.Restore(buf); err != nil {
+		t.Fatal(err)
+	}
+	if keys := r.KeysByTag(`user`); len(keys) != 1 || keys[0] != `a` {
+		t.Errorf("KeysByTag(user) = %v, want [a]", keys)
+	}
+	if n := r.InvalidateTag(`tenant:1`); n != 1 || r.Has(`a`) {
+		t.Errorf("InvalidateTag(tenant:1) = %d, Has(a) = %v, want 1, false", n, r.Has(`a`))
+	}
+	if !r.Has(`b`) || !r.Has(`c`) {
+		t.Error("InvalidateTag(tenant:1) removed untagged keys")
+	}
+}
+
+func TestRestoreOldVersion(t *testing.T) {
+	k, v := `a`, 1
+	key, _ := GobCodec{}.Marshal(&k)
+	val, _ := GobCodec{}.Marshal(&v)
+	for _, version := range []uint8{1, 2} {
+		buf := bytes.NewBufferString(snapshotMagic)
+		buf.WriteByte(version)
+		payload := binary.BigEndian.AppendUint64(nil, uint64(time.Now().Add(time.Hour).UnixNano()))
+		if version >= 2 {
+			payload = binary.BigEndian.AppendUint64(payload, uint64(time.Minute))
+			payload = binary.BigEndian.AppendUint64(payload, 0)
+		}
+		payload = binary.AppendUvarint(payload, uint64(len(key)))
+		payload = append(append(payload, key...), val...)
+		if err := writeRecord(buf, payload); err != nil {
+			t.Fatal(err)
+		}
+		_ = binary.Write(buf, binary.BigEndian, uint32(0))
+		r := NewTyped[string, int](WithInterval(time.Hour))
+		if err := r.Restore(buf); err != nil {
+			t.Fatalf("v%d: %v", version, err)
+		}
+		if ttl, has := r.TTL(`a`); !has || ttl <= 0 || ttl > time.Hour {
+			t.Errorf("v%d: TTL(a) = %v, %v", version, ttl, has)
+		}
+		if i := r.(*handle[string, int]).getShard(`a`).dict[`a`]; version >= 2 && i.slide != time.Minute {
+			t.Errorf("v%d: sliding = %v, want 1m", version, i.slide)
+		}
+		_ = r.Close()
+	}
+}
+
+func TestRestoreCorrupt(t *testing.T) {
+	c := NewTyped[string, string](WithInterval(time.Hour))
+	defer c.Close()
+	c.Set(`key`, `value`)
+	buf := new(bytes.Buffer)
+	if err := c.Snapshot(buf); err != nil {
+		t.Fatal(err)
+	}
+	snap := buf.Bytes()
+	head := len(snapshotMagic) + 1
+	size := binary.BigEndian.Uint32(snap[head:])
+	tests := []struct {
+		name string
+		edit func(b []byte) []byte
+		want error
+	}{
+		{`magic`, func(b []byte) []byte { b[0] = 'X'; return b }, ErrSnapshotFormat},
+		{`version`, func(b []byte) []byte { b[head-1] = 9; return b }, ErrSnapshotVersion},
+		{`max size`, func(b []byte) []byte { binary.BigEndian.PutUint32(b[head:], 0xFFFFFFFF); return b }, ErrSnapshotCorrupt},
+		{`large size`, func(b []byte) []byte { binary.BigEndian.PutUint32(b[head:], 200<<20); return b }, io.ErrUnexpectedEOF},
+		{`payload`, func(b []byte) []byte { b[head+4+int(size)-1] ^= 0xFF; return b }, ErrSnapshotCorrupt},
+		{`checksum`, func(b []byte) []byte { b[head+4+int(size)] ^= 0xFF; return b }, ErrSnapshotCorrupt},
+		{`truncated`, func(b []byte) []byte { return b[:head+4+int(size)/2] }, io.ErrUnexpectedEOF},
+		{`no end`, func(b []byte) []byte { return b[:len(b)-4] }, io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewTyped[string, string](WithInterval(time.Hour))
+			defer r.Close()
+			err := r.Restore(bytes.NewReader(tt.edit(bytes.Clone(snap))))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Restore() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCorruptSnapshot(t *testing.T) {
+	name := filepath.Join(t.TempDir(), `cache.snap`)
+	data := append([]byte(snapshotMagic), snapshotVersion, 0xFF, 0xFF, 0xFF, 0xFF)
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewTyped[string, int](WithInterval(time.Hour), WithSnapshot(name, time.Hour))
+	defer c.Close()
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len() = %d, want 0", n)
+	}
+}
@@ -0,0 +1,76 @@
+package ycache
+
+import (
+	"sync/atomic"
+)
+
+// Stats cache statistics
+type Stats struct {
+	Hits        uint64  // lookups that found a live key
+	Misses      uint64  // lookups that found nothing
+	Sets        uint64  // stored key-value
+	Deletes     uint64  // explicitly deleted key-value
+	Expirations uint64  // expired key-value
+	Evictions   uint64  // evicted key-value
+	Dropped     uint64  // events dropped by full subscriber buffers
+	Bytes       int64   // estimated memory size of all entries
+	Shards      []int   // entry count of each shard
+	ShardBytes  []int64 // estimated memory size of each shard
+}
+
+// HitRate returns hits / (hits + misses).
+func (s Stats) HitRate() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// Entries returns the entry count of all shards.
+func (s Stats) Entries() int {
+	count := 0
+	for _, n := range s.Shards {
+		count += n
+	}
+	return count
+}
+
+type counters struct {
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+	sets    atomic.Uint64
+	deletes atomic.Uint64
+	expired atomic.Uint64
+	evicted atomic.Uint64
+}
+
+func (c *counters) Lookup(has bool) {
+	if has {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+func (c *counters) Removed(reason Reason) {
+	switch reason {
+	case ReasonExpired:
+		c.expired.Add(1)
+	case ReasonEvicted:
+		c.evicted.Add(1)
+	}
+}
+
+// AddTo adds the counters to st, and resets them if reset is true.
+func (c *counters) AddTo(st *Stats, reset bool) {
+	load := (*atomic.Uint64).Load
+	if reset {
+		load = func(v *atomic.Uint64) uint64 { return v.Swap(0) }
+	}
+	st.Hits += load(&c.hits)
+	st.Misses += load(&c.misses)
+	st.Sets += load(&c.sets)
+	st.Deletes += load(&c.deletes)
+	st.Expirations += load(&c.expired)
+	st.Evictions += load(&c.evicted)
+}
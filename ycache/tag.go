@@ -0,0 +1,26 @@
+package ycache
+
+func (c *cache[K, V]) InvalidateTag(tag string) int {
+	if c.isClosed() {
+		return 0
+	}
+	count := 0
+	for _, s := range c.bucket {
+		keys := s.DelTag(c.opts, tag)
+		for _, key := range keys {
+			c.deleted(key)
+		}
+		count += len(keys)
+	}
+	return count
+}
+
+func (c *cache[K, V]) KeysByTag(tag string) (keys []K) {
+	if c.isClosed() {
+		return nil
+	}
+	for _, s := range c.bucket {
+		keys = append(keys, s.TagKeys(c.opts, tag)...)
+	}
+	return keys
+}
@@ -0,0 +1,397 @@
+package ycache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+/*
+  Minimal TCP key-value protocol, integers are big endian:
+
+    request:  op uint8 | ttl int64 | key size uint32 | key | val size uint32 | val
+    response: status uint8 | ttl int64 | val size uint32 | val
+
+  A connection sending opSub only receives response frames carrying published messages.
+*/
+
+const (
+	opGet uint8 = iota + 1
+	opSet
+	opDel
+	opTTL
+	opPub
+	opSub
+)
+
+const (
+	statusOK uint8 = iota
+	statusNotFound
+	statusError
+)
+
+const maxFrame = 64 << 20
+
+var errFrameSize = errors.New("ycache: frame too large")
+
+// TCPServer serves a MemoryBackend over TCP, meant for tests and examples.
+type TCPServer struct {
+	ln    net.Listener
+	mem   *MemoryBackend
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wait  sync.WaitGroup
+}
+
+// Addr returns the listening address.
+func (s *TCPServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops the server and closes all connections.
+func (s *TCPServer) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wait.Wait()
+	return err
+}
+
+// ListenTCP starts a TCPServer on addr, use "127.0.0.1:0" for a random port.
+func ListenTCP(addr string) (*TCPServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &TCPServer{
+		ln:    ln,
+		mem:   NewMemoryBackend(),
+		conns: make(map[net.Conn]struct{}),
+	}
+	s.wait.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// TCPBackend Backend and Broadcaster client of TCPServer, it reconnects on errors.
+type TCPBackend struct {
+	addr    string
+	timeout time.Duration
+	mu      sync.Mutex
+	conn    net.Conn
+	rd      *bufio.Reader
+}
+
+func (b *TCPBackend) Get(key string) (val []byte, ttl time.Duration, err error) {
+	status, ttl, val, err := b.do(opGet, key, nil, 0)
+	if err == nil && status == statusNotFound {
+		err = ErrNotFound
+	}
+	return val, ttl, err
+}
+
+func (b *TCPBackend) Set(key string, val []byte, ttl time.Duration) error {
+	_, _, _, err := b.do(opSet, key, val, ttl)
+	return err
+}
+
+func (b *TCPBackend) Del(key string) error {
+	_, _, _, err := b.do(opDel, key, nil, 0)
+	return err
+}
+
+func (b *TCPBackend) TTL(key string) (ttl time.Duration, err error) {
+	status, ttl, _, err := b.do(opTTL, key, nil, 0)
+	if err == nil && status == statusNotFound {
+		err = ErrNotFound
+	}
+	return ttl, err
+}
+
+func (b *TCPBackend) Publish(msg []byte) error {
+	_, _, _, err := b.do(opPub, "", msg, 0)
+	return err
+}
+
+func (b *TCPBackend) Subscribe(fn func(msg []byte)) (cancel func(), err error) {
+	conn, err := b.subscribe()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		mu     sync.Mutex
+		closed bool
+	)
+	go func() {
+		for cur := conn; ; {
+			rd := bufio.NewReader(cur)
+			for {
+				_, _, msg, err := readResponse(rd)
+				if err != nil {
+					break
+				}
+				fn(msg)
+			}
+			_ = cur.Close()
+			for {
+				mu.Lock()
+				done := closed
+				mu.Unlock()
+				if done {
+					return
+				}
+				next, err := b.subscribe()
+				if err != nil {
+					time.Sleep(b.timeout)
+					continue
+				}
+				mu.Lock()
+				if closed {
+					mu.Unlock()
+					_ = next.Close()
+					return
+				}
+				conn, cur = next, next
+				mu.Unlock()
+				break
+			}
+		}
+	}()
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		closed = true
+		_ = conn.Close()
+	}, nil
+}
+
+// Close closes the request connection.
+func (b *TCPBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn, b.rd = nil, nil
+	return err
+}
+
+// NewTCPBackend returns a client of the TCPServer at addr, it connects lazily.
+func NewTCPBackend(addr string, timeout ...time.Duration) *TCPBackend {
+	b := &TCPBackend{addr: addr, timeout: time.Second}
+	if len(timeout) > 0 && timeout[0] > 0 {
+		b.timeout = timeout[0]
+	}
+	return b
+}
+
+/*
+  Package private
+*/
+
+func (s *TCPServer) accept() {
+	defer s.wait.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wait.Add(1)
+		go s.serve(conn)
+	}
+}
+
+func (s *TCPServer) serve(conn net.Conn) {
+	defer s.wait.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+	rd := bufio.NewReader(conn)
+	for {
+		op, ttl, key, val, err := readRequest(rd)
+		if err != nil {
+			return
+		}
+		status, ttl, val := s.handle(op, key, val, ttl)
+		if op == opSub {
+			s.relay(conn)
+			return
+		}
+		if err = writeResponse(conn, status, ttl, val); err != nil {
+			return
+		}
+	}
+}
+
+func (s *TCPServer) handle(op uint8, key string, val []byte, ttl time.Duration) (uint8, time.Duration, []byte) {
+	var err error
+	switch op {
+	case opGet:
+		val, ttl, err = s.mem.Get(key)
+	case opSet:
+		err = s.mem.Set(key, val, ttl)
+	case opDel:
+		err = s.mem.Del(key)
+	case opTTL:
+		ttl, err = s.mem.TTL(key)
+	case opPub:
+		err = s.mem.Publish(val)
+	case opSub:
+	default:
+		err = errors.New("ycache: unknown op")
+	}
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return statusNotFound, 0, nil
+	case err != nil:
+		return statusError, 0, []byte(err.Error())
+	default:
+		return statusOK, ttl, val
+	}
+}
+
+// relay forwards published messages to conn until it fails.
+func (s *TCPServer) relay(conn net.Conn) {
+	msgs := make(chan []byte, DefaultEventBuffer)
+	cancel, _ := s.mem.Subscribe(func(msg []byte) {
+		select {
+		case msgs <- msg:
+		default:
+		}
+	})
+	defer cancel()
+	if writeResponse(conn, statusOK, 0, nil) != nil {
+		return
+	}
+	gone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		close(gone)
+	}()
+	for {
+		select {
+		case <-gone:
+			return
+		case msg := <-msgs:
+			if writeResponse(conn, statusOK, 0, msg) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (b *TCPBackend) do(op uint8, key string, val []byte, ttl time.Duration) (status uint8, rttl time.Duration, rval []byte, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for retry := 0; retry < 2; retry++ {
+		if b.conn == nil {
+			if b.conn, err = net.DialTimeout("tcp", b.addr, b.timeout); err != nil {
+				b.conn = nil
+				return
+			}
+			b.rd = bufio.NewReader(b.conn)
+		}
+		_ = b.conn.SetDeadline(time.Now().Add(b.timeout))
+		if err = writeRequest(b.conn, op, key, val, ttl); err == nil {
+			if status, rttl, rval, err = readResponse(b.rd); err == nil {
+				break
+			}
+		}
+		_ = b.conn.Close()
+		b.conn, b.rd = nil, nil
+	}
+	if err == nil && status == statusError {
+		err = errors.New(string(rval))
+	}
+	return status, rttl, rval, err
+}
+
+func (b *TCPBackend) subscribe() (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", b.addr, b.timeout)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(b.timeout))
+	if err = writeRequest(conn, opSub, "", nil, 0); err == nil {
+		_, _, _, err = readResponse(conn)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func writeRequest(w io.Writer, op uint8, key string, val []byte, ttl time.Duration) error {
+	buf := make([]byte, 0, 17+len(key)+len(val))
+	buf = append(buf, op)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(ttl))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(key)))
+	buf = append(buf, key...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(val)))
+	buf = append(buf, val...)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readRequest(r io.Reader) (op uint8, ttl time.Duration, key string, val []byte, err error) {
+	var head [13]byte
+	if _, err = io.ReadFull(r, head[:]); err != nil {
+		return
+	}
+	op = head[0]
+	ttl = time.Duration(binary.BigEndian.Uint64(head[1:]))
+	k, err := readBlock(r, binary.BigEndian.Uint32(head[9:]))
+	if err != nil {
+		return
+	}
+	var size [4]byte
+	if _, err = io.ReadFull(r, size[:]); err != nil {
+		return
+	}
+	val, err = readBlock(r, binary.BigEndian.Uint32(size[:]))
+	return op, ttl, string(k), val, err
+}
+
+func writeResponse(w io.Writer, status uint8, ttl time.Duration, val []byte) error {
+	buf := make([]byte, 0, 13+len(val))
+	buf = append(buf, status)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(ttl))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(val)))
+	buf = append(buf, val...)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readResponse(r io.Reader) (status uint8, ttl time.Duration, val []byte, err error) {
+	var head [13]byte
+	if _, err = io.ReadFull(r, head[:]); err != nil {
+		return
+	}
+	val, err = readBlock(r, binary.BigEndian.Uint32(head[9:]))
+	return head[0], time.Duration(binary.BigEndian.Uint64(head[1:])), val, err
+}
+
+func readBlock(r io.Reader, size uint32) ([]byte, error) {
+	if size > maxFrame {
+		return nil, errFrameSize
+	}
+	buf := make([]byte, size)
+	_, err := io.ReadFull(r, buf)
+	return buf, err
+}
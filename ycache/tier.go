@@ -0,0 +1,145 @@
+package ycache
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/azeroth-sha/y/yguid"
+)
+
+// tier connects the cache to the shared Backend and the Broadcaster of
+// invalidations, a message is the cache id followed by the encoded key.
+type tier[K comparable, V any] struct {
+	backend Backend
+	bcast   Broadcaster
+	id      []byte
+	cancel  func()
+}
+
+func (t *tier[K, V]) Close() {
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+/*
+  Package private
+*/
+
+// warm loads the key from the backend if it is missing locally.
+func (c *cache[K, V]) warm(key K) {
+	if c.tier == nil || c.tier.backend == nil {
+		return
+	}
+	if _, _, has := c.getShard(key).Peek(key); !has {
+		c.readThrough(key)
+	}
+}
+
+// readThrough loads the key from the backend into the local cache.
+func (c *cache[K, V]) readThrough(key K) (val V, has bool) {
+	if c.tier == nil || c.tier.backend == nil {
+		return val, false
+	}
+	data, ttl, err := c.tier.backend.Get(c.str(key))
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			c.opts.log.Errorf("ycache: backend get error: %v", err)
+		}
+		return val, false
+	}
+	if err = c.opts.codec.Unmarshal(data, &val); err != nil {
+		c.opts.log.Errorf("ycache: backend decode error: %v", err)
+		return val, false
+	}
+	s := c.getShard(key)
+	if ttl > 0 {
+		s.Set(c.opts, key, val, WithItemDur(ttl))
+	} else {
+		s.Set(c.opts, key, val)
+	}
+	return val, true
+}
+
+// stored writes the value set by the caller through to the backend, also
+// if the local cache refused it, and tells the other caches to drop the key.
+func (c *cache[K, V]) stored(key K, val V, exp time.Time) {
+	if c.tier == nil {
+		return
+	}
+	if b := c.tier.backend; b != nil {
+		str := c.str(key)
+		var err error
+		if !exp.IsZero() && !exp.After(time.Now()) {
+			err = b.Del(str)
+		} else if data, e := c.opts.codec.Marshal(&val); e != nil {
+			err = e
+		} else {
+			err = b.Set(str, data, remain(exp))
+		}
+		if err != nil {
+			c.opts.log.Errorf("ycache: backend write error: %v", err)
+		}
+	}
+	c.invalidate(key)
+}
+
+// deleted deletes the key from the backend and tells the other caches to drop it.
+func (c *cache[K, V]) deleted(key K) {
+	if c.tier == nil {
+		return
+	}
+	if b := c.tier.backend; b != nil {
+		if err := b.Del(c.str(key)); err != nil {
+			c.opts.log.Errorf("ycache: backend write error: %v", err)
+		}
+	}
+	c.invalidate(key)
+}
+
+// invalidate tells the other caches to drop the key.
+func (c *cache[K, V]) invalidate(key K) {
+	if c.tier.bcast == nil {
+		return
+	}
+	data, err := c.opts.codec.Marshal(&key)
+	if err == nil {
+		err = c.tier.bcast.Publish(append(append([]byte(nil), c.tier.id...), data...))
+	}
+	if err != nil {
+		c.opts.log.Errorf("ycache: broadcast error: %v", err)
+	}
+}
+
+// invalidated drops the key changed by another cache.
+func (c *cache[K, V]) invalidated(msg []byte) {
+	if len(msg) < len(c.tier.id) || bytes.HasPrefix(msg, c.tier.id) {
+		return
+	}
+	var key K
+	if err := c.opts.codec.Unmarshal(msg[len(c.tier.id):], &key); err != nil {
+		c.opts.log.Errorf("ycache: invalidation decode error: %v", err)
+		return
+	}
+	c.getShard(key).Del(c.opts, key)
+}
+
+func newTier[K comparable, V any](c *cache[K, V]) *tier[K, V] {
+	if c.opts.backend == nil && c.opts.bcast == nil {
+		return nil
+	}
+	t := &tier[K, V]{
+		backend: c.opts.backend,
+		bcast:   c.opts.bcast,
+		id:      yguid.New().Bytes(),
+	}
+	if t.bcast != nil {
+		cancel, err := t.bcast.Subscribe(c.invalidated)
+		if err != nil {
+			c.opts.log.Errorf("ycache: broadcast subscribe error: %v", err)
+		}
+		t.cancel = cancel
+	}
+	return t
+}
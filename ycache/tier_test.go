@@ -0,0 +1,103 @@
+package ycache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// eventually polls cond until it holds or a second passes.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+	}
+}
+
+func TestTCPTier(t *testing.T) {
+	srv, err := ListenTCP(`127.0.0.1:0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	newCache := func() TypedCache[string, int] {
+		b := NewTCPBackend(srv.Addr())
+		t.Cleanup(func() { _ = b.Close() })
+		c := NewTyped[string, int](WithInterval(time.Hour), WithBackend(b), WithBroadcaster(b))
+		t.Cleanup(func() { _ = c.Close() })
+		return c
+	}
+	a, b := newCache(), newCache()
+	check := NewTCPBackend(srv.Addr())
+	defer check.Close()
+
+	// write-through
+	a.Set(`k`, 1, WithItemDur(time.Hour))
+	if _, ttl, err := check.Get(`k`); err != nil || ttl <= 0 || ttl > time.Hour {
+		t.Fatalf("backend Get(k) = %v, %v, want a ttl up to an hour", ttl, err)
+	}
+
+	// read-through, the ttl comes along
+	if v, has := b.Get(`k`); !has || v != 1 {
+		t.Fatalf("b.Get(k) = %d, %v, want 1, true", v, has)
+	}
+	if ttl, has := b.TTL(`k`); !has || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("b.TTL(k) = %v, %v", ttl, has)
+	}
+
+	// invalidation drops the local copy of b, the next Get reads the new value
+	a.Set(`k`, 2)
+	eventually(t, `b to see k = 2`, func() bool {
+		v, _ := b.Get(`k`)
+		return v == 2
+	})
+	b.Set(`k`, 3)
+	eventually(t, `a to see k = 3`, func() bool {
+		v, _ := a.Get(`k`)
+		return v == 3
+	})
+
+	// delete
+	a.Del(`k`)
+	if _, _, err := check.Get(`k`); !errors.Is(err, ErrNotFound) {
+		t.Errorf("backend Get(k) after Del = %v, want ErrNotFound", err)
+	}
+	eventually(t, `b to drop k`, func() bool {
+		return !b.Has(`k`)
+	})
+}
+
+func TestTierRefusedSet(t *testing.T) {
+	m := NewMemoryBackend()
+	newCache := func(opts ...Option) TypedCache[string, int] {
+		opts = append([]Option{WithInterval(time.Hour), WithShardSize(1), WithBackend(m), WithBroadcaster(m)}, opts...)
+		c := NewTyped[string, int](opts...)
+		t.Cleanup(func() { _ = c.Close() })
+		return c
+	}
+	a, b := newCache(WithMaxCost(10)), newCache()
+	a.Set(`k`, 1, WithItemDur(time.Hour))
+	if v, has := b.Get(`k`); !has || v != 1 {
+		t.Fatalf("b.Get(k) = %d, %v, want 1, true", v, has)
+	}
+
+	// the local cache refuses the item, the backend still takes it
+	a.Set(`k`, 5, WithItemCost(100), WithItemDur(time.Hour))
+	data, ttl, err := m.Get(`k`)
+	if err != nil {
+		t.Fatalf("backend Get(k) after a refused Set = %v", err)
+	}
+	var v int
+	if err = (GobCodec{}).Unmarshal(data, &v); err != nil || v != 5 || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("backend Get(k) = %d, %v, %v, want 5 with a ttl up to an hour", v, ttl, err)
+	}
+	if v, has := a.Get(`k`); !has || v != 5 {
+		t.Errorf("a.Get(k) = %d, %v, want 5, true", v, has)
+	}
+	eventually(t, `b to see k = 5`, func() bool {
+		v, _ := b.Get(`k`)
+		return v == 5
+	})
+}
@@ -0,0 +1,85 @@
+package ycache
+
+const (
+	sketchDepth = 4
+	sketchWidth = 1 << 12
+)
+
+// sketch is a count-min sketch with periodic aging.
+type sketch struct {
+	rows    [sketchDepth][]uint8
+	added   int
+	samples int
+}
+
+func (s *sketch) indexes(h uint32) (idx [sketchDepth]uint32) {
+	h2 := h>>16 | h<<16 | 1
+	for i := range idx {
+		idx[i] = (h + uint32(i)*h2) & (sketchWidth - 1)
+	}
+	return idx
+}
+
+func (s *sketch) Incr(h uint32) {
+	for i, n := range s.indexes(h) {
+		if s.rows[i][n] < 0xFF {
+			s.rows[i][n]++
+		}
+	}
+	if s.added++; s.added >= s.samples {
+		s.reset()
+	}
+}
+
+func (s *sketch) Estimate(h uint32) uint8 {
+	est := uint8(0xFF)
+	for i, n := range s.indexes(h) {
+		est = min(est, s.rows[i][n])
+	}
+	return est
+}
+
+func (s *sketch) reset() {
+	for i := range s.rows {
+		for n := range s.rows[i] {
+			s.rows[i][n] >>= 1
+		}
+	}
+	s.added /= 2
+}
+
+type tinyLFU[K comparable] struct {
+	Evictor[K]
+	hash func(K) uint32
+	freq *sketch
+}
+
+func (t *tinyLFU[K]) Add(key K) {
+	t.freq.Incr(t.hash(key))
+	t.Evictor.Add(key)
+}
+
+func (t *tinyLFU[K]) Touch(key K) {
+	t.freq.Incr(t.hash(key))
+	t.Evictor.Touch(key)
+}
+
+func (t *tinyLFU[K]) Admit(key, victim K) bool {
+	h := t.hash(key)
+	t.freq.Incr(h)
+	return t.freq.Estimate(h) > t.freq.Estimate(t.hash(victim))
+}
+
+// NewTinyLFU returns a LRU evictor guarded by TinyLFU admission,
+// a new key is only stored if it is used more often than the victim.
+func NewTinyLFU[K comparable]() Evictor[K] {
+	s := &sketch{samples: sketchWidth * 10}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, sketchWidth)
+	}
+	return &tinyLFU[K]{
+		Evictor: NewLRU[K](),
+		hash:    newHasher[K](),
+		freq:    s,
+	}
+}
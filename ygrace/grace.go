@@ -5,9 +5,12 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/azeroth-sha/y/yguid"
 	"github.com/azeroth-sha/y/ylog"
 )
 
+// Service is run by Grace, Serv receives a logger with the service name and
+// the ID of the run attached, Down one with the service name.
 type Service interface {
 	Serv(ylog.Logger) error
 	Down(ylog.Logger) error
@@ -70,6 +73,7 @@ func New(opts ...Option) Grace {
 	for _, opt := range opts {
 		opt(&g.opts)
 	}
+	g.opts.log = ylog.Named(g.opts.log, `ygrace`)
 	return g
 }
 
@@ -78,7 +82,7 @@ func New(opts ...Option) Grace {
 */
 
 func (g *grace) down(name string, svr Service) {
-	log := g.opts.log
+	log := g.opts.log.With(`service`, name)
 	log.Infof("stopping: %s", name)
 	defer log.Infof("stopped: %s", name)
 	if err := svr.Down(log); err != nil {
@@ -89,11 +93,11 @@ func (g *grace) down(name string, svr Service) {
 func (g *grace) serv(name string, svr Service) {
 	defer g.wait.Done()
 	dur := g.opts.dur
-	log := g.opts.log
+	log := g.opts.log.With(`service`, name)
 EXIT:
 	for atomic.LoadInt32(&g.running) == 1 {
 		log.Infof("starting: %s", name)
-		g.run(log, name, svr)()
+		g.run(log.With(`run_id`, yguid.String()), name, svr)()
 		if dur > 0 {
 			log.Infof("waiting: %s -> %s", name, dur)
 			select {
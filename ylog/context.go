@@ -0,0 +1,59 @@
+package ylog
+
+import (
+	"context"
+	"sync"
+)
+
+// Extractor returns key-value pairs read from ctx, e.g. a request or trace ID
+type Extractor func(ctx context.Context) (kv []any)
+
+// RegisterExtractor adds fn to the extractors run by FromContext and the slog handler
+func RegisterExtractor(fn Extractor) {
+	if fn == nil {
+		return
+	}
+	extMu.Lock()
+	defer extMu.Unlock()
+	extractors = append(extractors[:len(extractors):len(extractors)], fn)
+}
+
+// NewContext returns a copy of ctx carrying l
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the default one, with
+// the fields of the registered extractors attached.
+func FromContext(ctx context.Context) Logger {
+	l, ok := ctx.Value(ctxKey{}).(Logger)
+	if !ok || l == nil {
+		l = DefaultLog()
+	}
+	if kv := extract(ctx); len(kv) > 0 {
+		l = l.With(kv...)
+	}
+	return l
+}
+
+/*
+  Package private
+*/
+
+type ctxKey struct{}
+
+var (
+	extMu      = new(sync.RWMutex)
+	extractors []Extractor
+)
+
+// extract returns the fields of all extractors for ctx.
+func extract(ctx context.Context) (kv []any) {
+	extMu.RLock()
+	fns := extractors
+	extMu.RUnlock()
+	for _, fn := range fns {
+		kv = append(kv, fn(ctx)...)
+	}
+	return kv
+}
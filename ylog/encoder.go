@@ -0,0 +1,204 @@
+package ylog
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Record is a log line handed to an Encoder
+type Record struct {
+	Level  Level  // 日志等级
+	Time   string // 格式化后的时间，未启用时为空
+	File   string // 调用位置，未启用时为空
+	Name   string // 日志名称，未命名时为空
+	Msg    string // 日志内容
+	Stack  string // 堆栈，未启用时为空
+	Fields []any  // 键值字段
+}
+
+// Encoder writes a Record as one line to the buffer
+type Encoder interface {
+	Encode(b *bytes.Buffer, r *Record)
+}
+
+// TextEncoder writes logfmt-like lines: level=Info time=... file=... logger=... k=v stack=... msg=...
+type TextEncoder struct{}
+
+func (TextEncoder) Encode(b *bytes.Buffer, r *Record) {
+	b.WriteString(`level=`)
+	b.WriteString(r.Level.String())
+	b.WriteString(` `)
+	if r.Time != "" {
+		b.WriteString(`time=`)
+		b.WriteString(r.Time)
+		b.WriteString(` `)
+	}
+	if r.File != "" {
+		b.WriteString(`file=`)
+		b.WriteString(r.File)
+		b.WriteString(` `)
+	}
+	if r.Name != "" {
+		b.WriteString(`logger=`)
+		appendText(b, r.Name)
+		b.WriteString(` `)
+	}
+	appendFields(b, r.Fields)
+	if r.Stack != "" {
+		b.WriteString(`stack=`)
+		appendText(b, r.Stack)
+		b.WriteString(` `)
+	}
+	b.WriteString(`msg=`)
+	b.WriteString(r.Msg)
+	b.WriteString("\r\n")
+}
+
+// JSONEncoder writes JSON lines: {"level":"Info","time":...,"caller":...,"logger":...,"msg":...,"k":v,"stack":...},
+// fields named like the built-in keys are written as "fields.<key>"
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(b *bytes.Buffer, r *Record) {
+	b.WriteString(`{"level":`)
+	appendJSONString(b, r.Level.String())
+	if r.Time != "" {
+		b.WriteString(`,"time":`)
+		appendJSONString(b, r.Time)
+	}
+	if r.File != "" {
+		b.WriteString(`,"caller":`)
+		appendJSONString(b, r.File)
+	}
+	if r.Name != "" {
+		b.WriteString(`,"logger":`)
+		appendJSONString(b, r.Name)
+	}
+	b.WriteString(`,"msg":`)
+	appendJSONString(b, r.Msg)
+	for kv := r.Fields; len(kv) > 0; {
+		key, val := badKey, kv[0]
+		if s, ok := kv[0].(string); ok && len(kv) > 1 {
+			key, val = s, kv[1]
+			kv = kv[2:]
+		} else {
+			kv = kv[1:]
+		}
+		b.WriteByte(',')
+		appendJSONString(b, jsonKey(key))
+		b.WriteByte(':')
+		appendJSONValue(b, val)
+	}
+	if r.Stack != "" {
+		b.WriteString(`,"stack":`)
+		appendJSONString(b, r.Stack)
+	}
+	b.WriteString("}\n")
+}
+
+/*
+  Package private
+*/
+
+const hexDigits = `0123456789abcdef`
+
+// jsonKey renames a field key colliding with a built-in key of JSONEncoder.
+func jsonKey(key string) string {
+	switch key {
+	case `level`, `time`, `caller`, `logger`, `msg`, `stack`:
+		return `fields.` + key
+	default:
+		return key
+	}
+}
+
+// appendJSONValue writes numbers, booleans and nil as is, the others as strings.
+func appendJSONValue(b *bytes.Buffer, v any) {
+	var num []byte
+	switch val := v.(type) {
+	case nil:
+		b.WriteString(`null`)
+		return
+	case bool:
+		num = strconv.AppendBool(b.AvailableBuffer(), val)
+	case int:
+		num = strconv.AppendInt(b.AvailableBuffer(), int64(val), 10)
+	case int8:
+		num = strconv.AppendInt(b.AvailableBuffer(), int64(val), 10)
+	case int16:
+		num = strconv.AppendInt(b.AvailableBuffer(), int64(val), 10)
+	case int32:
+		num = strconv.AppendInt(b.AvailableBuffer(), int64(val), 10)
+	case int64:
+		num = strconv.AppendInt(b.AvailableBuffer(), val, 10)
+	case uint:
+		num = strconv.AppendUint(b.AvailableBuffer(), uint64(val), 10)
+	case uint8:
+		num = strconv.AppendUint(b.AvailableBuffer(), uint64(val), 10)
+	case uint16:
+		num = strconv.AppendUint(b.AvailableBuffer(), uint64(val), 10)
+	case uint32:
+		num = strconv.AppendUint(b.AvailableBuffer(), uint64(val), 10)
+	case uint64:
+		num = strconv.AppendUint(b.AvailableBuffer(), val, 10)
+	case float32:
+		if f := float64(val); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			num = strconv.AppendFloat(b.AvailableBuffer(), f, 'g', -1, 32)
+		}
+	case float64:
+		if !math.IsNaN(val) && !math.IsInf(val, 0) {
+			num = strconv.AppendFloat(b.AvailableBuffer(), val, 'g', -1, 64)
+		}
+	}
+	if num != nil {
+		b.Write(num)
+	} else {
+		appendJSONString(b, valueString(v))
+	}
+}
+
+// appendJSONString writes s quoted, invalid UTF-8 is replaced by U+FFFD.
+func appendJSONString(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case '\n':
+				b.WriteString(`\n`)
+			case '\r':
+				b.WriteString(`\r`)
+			case '\t':
+				b.WriteString(`\t`)
+			default:
+				b.WriteString(`\u00`)
+				b.WriteByte(hexDigits[c>>4])
+				b.WriteByte(hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && n == 1 {
+			b.WriteString(s[start:i])
+			b.WriteString(`�`)
+			i++
+			start = i
+			continue
+		}
+		i += n
+	}
+	b.WriteString(s[start:])
+	b.WriteByte('"')
+}
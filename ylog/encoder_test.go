@@ -0,0 +1,57 @@
+package ylog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// jsonKeys returns the keys of a JSON object line in order.
+func jsonKeys(t *testing.T, line string) []string {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(line))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		t.Fatalf("line %q is not an object: %v", line, err)
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		keys = append(keys, tok.(string))
+		var val json.RawMessage
+		if err = dec.Decode(&val); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+	}
+	return keys
+}
+
+func TestJSONReservedKeys(t *testing.T) {
+	buf := new(lineBuffer)
+	l := NewLogger(WithWriter(buf), WithEncoder(JSONEncoder{}), WithTime(true), WithFile(true, true), WithStack(LevelError))
+	l = l.(*Entry).Named(`svc`).With(`logger`, `other`)
+	l.Errorw(`x`, `msg`, `dup`, `level`, `dup`, `time`, `dup`, `caller`, `dup`, `stack`, `dup`, `k`, 1)
+	line := buf.String()
+	keys := jsonKeys(t, line)
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if seen[k] {
+			t.Errorf("duplicate key %q in %s", k, line)
+		}
+		seen[k] = true
+	}
+	for _, k := range []string{`fields.msg`, `fields.level`, `fields.time`, `fields.caller`, `fields.logger`, `fields.stack`, `k`} {
+		if !seen[k] {
+			t.Errorf("key %q missing in %s", k, line)
+		}
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(line), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m[`msg`] != `x` || m[`level`] != `Error` || m[`logger`] != `svc` || m[`fields.msg`] != `dup` || m[`fields.logger`] != `other` {
+		t.Errorf("decoded %v", m)
+	}
+}
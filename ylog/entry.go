@@ -7,83 +7,232 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/azeroth-sha/y/ybuff"
 )
 
 type Entry struct {
-	level   Level          // 日志等级
+	level   *LevelVar      // 日志等级
+	name    string         // 日志名称
+	named   []*LevelVar    // 按名称覆盖的等级，由近及远
 	outFile bool           // 输出文件名
-	fileDep int            // 文件深度
+	skip    int            // 额外跳过的调用层数
 	fileLin bool           // 文件行号
+	stackLv Level          // 附加堆栈的等级，0 为不附加
 	outTime bool           // 输出时间
 	timeFmt string         // 时间格式
 	output  io.WriteCloser // 日志输出
+	encoder Encoder        // 日志编码
+	fields  []any          // 附加字段
+	queue   int            // 异步队列长度
+	drop    bool           // 队列满时丢弃
+	sink    *sink          // 与子日志共享的输出
+}
+
+// With returns a child logger writing the key-value pairs on each line
+func (e *Entry) With(kv ...any) Logger {
+	c := *e
+	c.fields = append(e.fields[:len(e.fields):len(e.fields)], kv...)
+	return &c
 }
 
 func (e *Entry) Debug(args ...any) {
-	buf := e.outBuf(LevelDebug, args...)
-	defer ybuff.Put(buf)
-	e.wrtBuf(buf)
+	if e.Enabled(LevelDebug) {
+		e.emit(LevelDebug, fmt.Sprint(args...), nil)
+	}
 }
 
 func (e *Entry) Debugf(format string, args ...any) {
-	buf := e.outFmtBuf(LevelDebug, format, args...)
-	defer ybuff.Put(buf)
-	e.wrtBuf(buf)
+	if e.Enabled(LevelDebug) {
+		e.emit(LevelDebug, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (e *Entry) Debugw(msg string, kv ...any) {
+	if e.Enabled(LevelDebug) {
+		e.emit(LevelDebug, msg, kv)
+	}
 }
 
 func (e *Entry) Info(args ...any) {
-	buf := e.outBuf(LevelInfo, args...)
-	defer ybuff.Put(buf)
-	e.wrtBuf(buf)
+	if e.Enabled(LevelInfo) {
+		e.emit(LevelInfo, fmt.Sprint(args...), nil)
+	}
 }
 
 func (e *Entry) Infof(format string, args ...any) {
-	buf := e.outFmtBuf(LevelInfo, format, args...)
-	defer ybuff.Put(buf)
-	e.wrtBuf(buf)
+	if e.Enabled(LevelInfo) {
+		e.emit(LevelInfo, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (e *Entry) Infow(msg string, kv ...any) {
+	if e.Enabled(LevelInfo) {
+		e.emit(LevelInfo, msg, kv)
+	}
 }
 
 func (e *Entry) Warn(args ...any) {
-	buf := e.outBuf(LevelWarn, args...)
-	defer ybuff.Put(buf)
-	e.wrtBuf(buf)
+	if e.Enabled(LevelWarn) {
+		e.emit(LevelWarn, fmt.Sprint(args...), nil)
+	}
 }
 
 func (e *Entry) Warnf(format string, args ...any) {
-	buf := e.outFmtBuf(LevelWarn, format, args...)
-	defer ybuff.Put(buf)
-	e.wrtBuf(buf)
+	if e.Enabled(LevelWarn) {
+		e.emit(LevelWarn, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (e *Entry) Warnw(msg string, kv ...any) {
+	if e.Enabled(LevelWarn) {
+		e.emit(LevelWarn, msg, kv)
+	}
 }
 
 func (e *Entry) Error(args ...any) {
-	buf := e.outBuf(LevelError, args...)
-	defer ybuff.Put(buf)
-	e.wrtBuf(buf)
+	if e.Enabled(LevelError) {
+		e.emit(LevelError, fmt.Sprint(args...), nil)
+	}
 }
 
 func (e *Entry) Errorf(format string, args ...any) {
-	buf := e.outFmtBuf(LevelError, format, args...)
-	defer ybuff.Put(buf)
-	e.wrtBuf(buf)
+	if e.Enabled(LevelError) {
+		e.emit(LevelError, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (e *Entry) Errorw(msg string, kv ...any) {
+	if e.Enabled(LevelError) {
+		e.emit(LevelError, msg, kv)
+	}
+}
+
+// Panic logs the line and panics with its message.
+func (e *Entry) Panic(args ...any) {
+	msg := fmt.Sprint(args...)
+	if e.Enabled(LevelPanic) {
+		e.emit(LevelPanic, msg, nil)
+	}
+	panic(msg)
+}
+
+func (e *Entry) Panicf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if e.Enabled(LevelPanic) {
+		e.emit(LevelPanic, msg, nil)
+	}
+	panic(msg)
+}
+
+func (e *Entry) Panicw(msg string, kv ...any) {
+	if e.Enabled(LevelPanic) {
+		e.emit(LevelPanic, msg, kv)
+	}
+	panic(msg)
+}
+
+// Fatal logs the line, syncs the output and exits with status 1.
+func (e *Entry) Fatal(args ...any) {
+	if e.Enabled(LevelFatal) {
+		e.emit(LevelFatal, fmt.Sprint(args...), nil)
+	}
+	e.exit()
+}
+
+func (e *Entry) Fatalf(format string, args ...any) {
+	if e.Enabled(LevelFatal) {
+		e.emit(LevelFatal, fmt.Sprintf(format, args...), nil)
+	}
+	e.exit()
+}
+
+func (e *Entry) Fatalw(msg string, kv ...any) {
+	if e.Enabled(LevelFatal) {
+		e.emit(LevelFatal, msg, kv)
+	}
+	e.exit()
+}
+
+// Log writes msg with kv at level l, Panic and Fatal lines neither panic nor exit.
+func (e *Entry) Log(l Level, msg string, kv ...any) {
+	if e.Enabled(l) {
+		e.emit(l, msg, kv)
+	}
+}
+
+// Enabled returns whether lines of level l are written.
+func (e *Entry) Enabled(l Level) bool {
+	for _, v := range e.named {
+		if o := v.Level(); o != 0 {
+			return o.rank() >= l.rank()
+		}
+	}
+	return e.level.Level().rank() >= l.rank()
+}
+
+// Named returns a child logger named name, or "parent.name" if the logger is
+// named, whose level can be overridden by SetNamedLevel.
+func (e *Entry) Named(name string) Logger {
+	c := *e
+	if e.name != "" {
+		name = e.name + `.` + name
+	}
+	c.name = name
+	c.named = namedVars(name)
+	return &c
+}
+
+// AddCallerSkip returns a child logger reporting the caller n more frames up, for wrappers.
+func (e *Entry) AddCallerSkip(n int) Logger {
+	c := *e
+	c.skip += n
+	return &c
+}
+
+// LevelVar returns the level handle shared with the child loggers.
+func (e *Entry) LevelVar() *LevelVar {
+	return e.level
+}
+
+// Sync waits for the queued lines to be written and syncs the output if it is a Syncer.
+func (e *Entry) Sync() error {
+	return e.sink.Sync()
+}
+
+// Close flushes and closes the output shared with the child loggers.
+func (e *Entry) Close() error {
+	return e.sink.Close()
+}
+
+// Dropped returns the number of lines dropped by a full queue or after Close.
+func (e *Entry) Dropped() uint64 {
+	return e.sink.dropped.Load()
+}
+
+// Failed returns the number of lines the output failed to write.
+func (e *Entry) Failed() uint64 {
+	return e.sink.failed.Load()
 }
 
 // NewLogger returns a new logger
 func NewLogger(opts ...Option) Logger {
 	l := &Entry{
-		level:   LevelInfo,
+		level:   NewLevelVar(LevelInfo),
 		outFile: false,
-		fileDep: 4,
 		fileLin: true,
 		outTime: true,
 		timeFmt: time.RFC3339,
 		output:  &nopCloseWriter{os.Stdout},
+		encoder: TextEncoder{},
 	}
 	for _, opt := range opts {
 		opt(l)
 	}
+	l.sink = newSink(l.output, l.queue, l.drop)
 	return l
 }
 
@@ -91,65 +240,97 @@ func NewLogger(opts ...Option) Logger {
   Package private
 */
 
-func (e *Entry) wrtBuf(b *bytes.Buffer) {
-	if b == nil {
-		return
-	}
-	_, _ = b.WriteString("\r\n")
-	_, _ = b.WriteTo(e.output)
+// emit writes a line logged by the caller of the Entry method calling it.
+func (e *Entry) emit(l Level, msg string, kv []any) {
+	// skip emit and the Entry method
+	e.sink.write(l, e.encode(2, l, msg, kv))
+}
+
+// emitPC writes a line logged at pc, stack is written if l is stacked.
+func (e *Entry) emitPC(pc uintptr, stack []uintptr, l Level, msg string, kv []any) {
+	e.sink.write(l, e.encodePC(pc, stack, l, msg, kv))
 }
 
-func (e *Entry) outBuf(l Level, args ...any) *bytes.Buffer {
-	if e.level < l {
-		return nil
+// encode returns a line logged skip frames above the caller of encode.
+func (e *Entry) encode(skip int, l Level, msg string, kv []any) *bytes.Buffer {
+	var pcs [maxStack]uintptr
+	var n int
+	if e.outFile || e.stacked(l) {
+		// skip runtime.Callers and encode
+		n = runtime.Callers(2+skip+e.skip, pcs[:])
 	}
-	buf := ybuff.Get()
-	e.wrtPre(buf, l)
-	buf.WriteString(`msg=`)
-	buf.WriteString(fmt.Sprint(args...))
-	return buf
+	var pc uintptr
+	if n > 0 {
+		pc = pcs[0]
+	}
+	return e.encodePC(pc, pcs[:n], l, msg, kv)
 }
 
-func (e *Entry) outFmtBuf(l Level, format string, args ...any) *bytes.Buffer {
-	if e.level < l {
-		return nil
+// encodePC returns a line logged at pc, stack is written if l is stacked.
+func (e *Entry) encodePC(pc uintptr, stack []uintptr, l Level, msg string, kv []any) *bytes.Buffer {
+	r := Record{Level: l, Name: e.name, Msg: msg, Fields: e.fields}
+	if len(kv) > 0 {
+		r.Fields = append(e.fields[:len(e.fields):len(e.fields)], kv...)
+	}
+	if e.outTime {
+		r.Time = time.Now().Format(e.timeFmt)
+	}
+	if e.outFile && pc != 0 {
+		r.File = e.fileName(pc)
+	}
+	if e.stacked(l) && len(stack) > 0 {
+		r.Stack = formatStack(stack)
 	}
 	buf := ybuff.Get()
-	e.wrtPre(buf, l)
-	buf.WriteString(`msg=`)
-	buf.WriteString(fmt.Sprintf(format, args...))
+	e.encoder.Encode(buf, &r)
 	return buf
 }
 
-func (e *Entry) wrtPre(b *bytes.Buffer, l Level) {
-	b.WriteString(`level=`)
-	b.WriteString(l.String())
-	b.WriteString(` `)
-	if e.outTime {
-		b.WriteString(`time=`)
-		b.WriteString(time.Now().Format(e.timeFmt))
-		b.WriteString(` `)
-	}
-	if e.outFile {
-		name, line := e.callInfo()
-		b.WriteString(`file=`)
-		b.WriteString(name)
-		if e.fileLin {
-			b.WriteString(`:`)
-			b.WriteString(fmt.Sprint(line))
-		}
-		b.WriteString(` `)
+// stacked returns whether lines of level l carry a stack trace.
+func (e *Entry) stacked(l Level) bool {
+	return e.stackLv != 0 && l.rank() <= e.stackLv.rank()
+}
+
+// exit syncs the output and exits, see Fatal.
+func (e *Entry) exit() {
+	_ = e.Sync()
+	os.Exit(1)
+}
+
+// fileName returns "dir/file.go:line" of pc.
+func (e *Entry) fileName(pc uintptr) string {
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if f.File == "" {
+		return ""
+	}
+	d, n := path.Split(f.File)
+	name := path.Join(path.Base(d), n)
+	if e.fileLin {
+		name += `:` + strconv.Itoa(f.Line)
 	}
+	return name
 }
 
-func (e *Entry) callInfo() (string, int) {
-	_, file, line, ok := runtime.Caller(e.fileDep)
-	if !ok {
-		return "", 0
-	} else {
-		d, n := path.Split(file)
-		d = path.Base(d)
-		return path.Join(d, n), line
+// maxStack is the max number of frames of a stack trace
+const maxStack = 64
+
+// formatStack returns the function and the tab indented file:line of each frame, as a panic does.
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(f.Function)
+		b.WriteString("\n\t")
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+		if !more {
+			return b.String()
+		}
 	}
 }
 
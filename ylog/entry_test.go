@@ -0,0 +1,204 @@
+package ylog
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lineBuffer is an io.WriteCloser keeping the written lines.
+type lineBuffer struct {
+	bytes.Buffer
+}
+
+func (*lineBuffer) Close() error { return nil }
+
+// line returns the line it is called from.
+func line() int {
+	_, _, n, _ := runtime.Caller(1)
+	return n
+}
+
+var fileRe = regexp.MustCompile(`file=(\S+)`)
+
+func TestCallerFile(t *testing.T) {
+	buf := new(lineBuffer)
+	l := NewLogger(WithWriter(buf), WithLevel(LevelDebug), WithTime(false), WithFile(true, true))
+	sample := NewSampler(l, time.Hour, 100, 0)
+	defer sample.Close()
+	teeSample := NewSampler(NewTee(l), time.Hour, 100, 0)
+	defer teeSample.Close()
+	tests := []struct {
+		name string
+		log  func() int // logs one line and returns the line of the call
+	}{
+		{`Info`, func() int {
+			l.Info(`x`)
+			return line() - 1
+		}},
+		{`Infof`, func() int {
+			l.Infof(`%s`, `x`)
+			return line() - 1
+		}},
+		{`Infow`, func() int {
+			l.Infow(`x`, `k`, 1)
+			return line() - 1
+		}},
+		{`Log`, func() int {
+			l.Log(LevelWarn, `x`)
+			return line() - 1
+		}},
+		{`Panic`, func() (n int) {
+			defer func() { _ = recover() }()
+			n = line() + 1
+			l.Panic(`x`)
+			return n
+		}},
+		{`With`, func() int {
+			l.With(`k`, 1).Info(`x`)
+			return line() - 1
+		}},
+		{`Named`, func() int {
+			Named(Named(l, `a`), `b`).Error(`x`)
+			return line() - 1
+		}},
+		{`Tee`, func() int {
+			tl := NewTee(l)
+			defer tl.Sync()
+			tl.Info(`x`)
+			return line() - 1
+		}},
+		{`TeeWith`, func() int {
+			tl := NewTee(l)
+			defer tl.Sync()
+			tl.With(`k`, 1).Warnw(`x`)
+			return line() - 1
+		}},
+		{`TeeNamed`, func() int {
+			tl := NewTee(l)
+			defer tl.Sync()
+			Named(tl, `a`).Debugf(`x`)
+			return line() - 1
+		}},
+		{`TeeLog`, func() int {
+			tl := NewTee(l)
+			defer tl.Sync()
+			tl.Log(LevelInfo, `x`)
+			return line() - 1
+		}},
+		{`TeeNested`, func() int {
+			tl := NewTee(NewTee(l), NewTee())
+			defer tl.Sync()
+			tl.Info(`x`)
+			return line() - 1
+		}},
+		{`TeePanic`, func() (n int) {
+			defer func() { _ = recover() }()
+			n = line() + 1
+			NewTee(l).Panicw(`x`)
+			return n
+		}},
+		{`Sampler`, func() int {
+			sample.Info(`x`)
+			return line() - 1
+		}},
+		{`SamplerWith`, func() int {
+			sample.With(`k`, 1).Errorf(`x`)
+			return line() - 1
+		}},
+		{`SamplerTee`, func() int {
+			defer teeSample.Sync()
+			teeSample.Infow(`x`)
+			return line() - 1
+		}},
+		{`SlogHandler`, func() int {
+			slog.New(NewSlogHandler(l)).Info(`x`)
+			return line() - 1
+		}},
+		{`SlogHandlerWith`, func() int {
+			slog.New(NewSlogHandler(l)).With(`k`, 1).WithGroup(`g`).Warn(`x`, `k`, 2)
+			return line() - 1
+		}},
+		{`SlogHandlerTee`, func() int {
+			tl := NewTee(l)
+			defer tl.Sync()
+			slog.New(NewSlogHandler(tl)).Error(`x`)
+			return line() - 1
+		}},
+		{`FromSlog`, func() int {
+			FromSlog(slog.New(NewSlogHandler(l))).Info(`x`)
+			return line() - 1
+		}},
+		{`FromSlogWith`, func() int {
+			FromSlog(slog.New(NewSlogHandler(l))).With(`k`, 1).Infow(`x`)
+			return line() - 1
+		}},
+		{`FromSlogTee`, func() int {
+			tl := NewTee(l)
+			defer tl.Sync()
+			NewTee(FromSlog(slog.New(NewSlogHandler(tl)))).Warnf(`x`)
+			return line() - 1
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			want := `ylog/entry_test.go:` + strconv.Itoa(tt.log())
+			out := strings.TrimSpace(buf.String())
+			if strings.Count(out, "\n") != 0 {
+				t.Fatalf("want one line, got %q", out)
+			}
+			if m := fileRe.FindStringSubmatch(out); m == nil || m[1] != want {
+				t.Errorf("file of %q, want %s", out, want)
+			}
+		})
+	}
+}
+
+func TestStack(t *testing.T) {
+	buf := new(lineBuffer)
+	l := NewLogger(WithWriter(buf), WithTime(false), WithStack(LevelError))
+	l.Warn(`no stack`)
+	if strings.Contains(buf.String(), `stack=`) {
+		t.Errorf("stack below the stack level: %q", buf.String())
+	}
+	buf.Reset()
+	tl := NewTee(l)
+	tl.Error(`stack`)
+	_ = tl.Sync()
+	out := buf.String()
+	if !strings.Contains(out, `stack=`) || !strings.Contains(out, `TestStack`) {
+		t.Errorf("no stack of the caller: %q", out)
+	}
+	if strings.Contains(out, `ylog.(*tee)`) {
+		t.Errorf("stack holds the tee frames: %q", out)
+	}
+}
+
+func TestLevelOrder(t *testing.T) {
+	for l, want := range map[Level]Level{LevelError: 1, LevelWarn: 2, LevelInfo: 3, LevelDebug: 4} {
+		if l != want {
+			t.Errorf("%v = %d, want the former value %d", l, l, want)
+		}
+	}
+	levels := []Level{LevelFatal, LevelPanic, LevelError, LevelWarn, LevelInfo, LevelDebug}
+	for i, level := range levels {
+		e := NewLogger(WithLevel(level), WithStack(level)).(*Entry)
+		for j, l := range levels {
+			if got := e.Enabled(l); got != (j <= i) {
+				t.Errorf("logger of level %v: Enabled(%v) = %v", level, l, got)
+			}
+			if got := e.stacked(l); got != (j <= i) {
+				t.Errorf("stack level %v: stacked(%v) = %v", level, l, got)
+			}
+		}
+		if p, err := ParseLevel(level.String()); err != nil || p != level {
+			t.Errorf("ParseLevel(%v) = %v, %v", level, p, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package ylog
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+  Package private
+*/
+
+// badKey is the key of a value not preceded by a string key
+const badKey = `!BADKEY`
+
+// appendFields writes the key-value pairs as logfmt, each followed by a space.
+func appendFields(b *bytes.Buffer, kv []any) {
+	for len(kv) > 0 {
+		key, val := badKey, kv[0]
+		if s, ok := kv[0].(string); ok && len(kv) > 1 {
+			key, val = s, kv[1]
+			kv = kv[2:]
+		} else {
+			kv = kv[1:]
+		}
+		appendText(b, key)
+		b.WriteByte('=')
+		appendText(b, valueString(val))
+		b.WriteByte(' ')
+	}
+}
+
+// appendText writes s, quoted if it is empty or holds spaces, quotes, `=` or control characters.
+func appendText(b *bytes.Buffer, s string) {
+	if needQuote(s) {
+		b.WriteString(strconv.Quote(s))
+	} else {
+		b.WriteString(s)
+	}
+}
+
+func needQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || !strconv.IsPrint(r) {
+			return true
+		}
+		i += n
+	}
+	return false
+}
+
+// valueString formats the common types without reflection.
+func valueString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return `<nil>`
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case int:
+		return strconv.FormatInt(int64(val), 10)
+	case int8:
+		return strconv.FormatInt(int64(val), 10)
+	case int16:
+		return strconv.FormatInt(int64(val), 10)
+	case int32:
+		return strconv.FormatInt(int64(val), 10)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case uint:
+		return strconv.FormatUint(uint64(val), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(val), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(val), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(val), 10)
+	case uint64:
+		return strconv.FormatUint(val, 10)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case error:
+		return val.Error()
+	case fmt.Stringer:
+		return val.String()
+	default:
+		return fmt.Sprint(val)
+	}
+}
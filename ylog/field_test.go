@@ -0,0 +1,50 @@
+package ylog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAppendFields(t *testing.T) {
+	tests := []struct {
+		name string
+		kv   []any
+		want string
+	}{
+		{`plain`, []any{`k`, `v`}, `k=v `},
+		{`space`, []any{`k`, `a b`}, `k="a b" `},
+		{`equals`, []any{`k`, `a=b`}, `k="a=b" `},
+		{`quote`, []any{`k`, `say "hi"`}, `k="say \"hi\"" `},
+		{`backslash`, []any{`k`, `a\b`}, `k=a\b `},
+		{`newline`, []any{`k`, "a\nb"}, `k="a\nb" `},
+		{`tab`, []any{`k`, "a\tb"}, `k="a\tb" `},
+		{`control`, []any{`k`, "a\x00b\x7f"}, `k="a\x00b\x7f" `},
+		{`empty`, []any{`k`, ``}, `k="" `},
+		{`unicode`, []any{`k`, `值`}, `k=值 `},
+		{`non printable`, []any{`k`, "a\u200bb"}, `k="a\u200bb" `},
+		{`invalid utf8`, []any{`k`, "a\xffb"}, `k="a\xffb" `},
+		{`key space`, []any{`a key`, 1}, `"a key"=1 `},
+		{`key equals`, []any{`a=b`, 1}, `"a=b"=1 `},
+		{`empty key`, []any{``, 1}, `""=1 `},
+		{`odd`, []any{`a`, 1, `b`}, `a=1 !BADKEY=b `},
+		{`non string key`, []any{42, `v`}, `!BADKEY=42 !BADKEY=v `},
+		{`non string key pair`, []any{42, `k`, `v`}, `!BADKEY=42 k=v `},
+		{`types`, []any{`n`, nil, `b`, true, `i`, -3, `u`, uint8(7), `f`, 1.5, `e`, errors.New(`bad thing`), `bs`, []byte(`x y`)},
+			`n=<nil> b=true i=-3 u=7 f=1.5 e="bad thing" bs="x y" `},
+		{`time`, []any{`t`, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, `t=2024-01-02T03:04:05Z `},
+		{`stringer`, []any{`d`, time.Second}, `d=1s `},
+		{`struct`, []any{`s`, struct{ A, B int }{1, 2}}, `s="{1 2}" `},
+		{`none`, nil, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			appendFields(b, tt.kv)
+			if got := b.String(); got != tt.want {
+				t.Errorf("appendFields(%q) = %s, want %s", tt.kv, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package ylog
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/azeroth-sha/y/yfile"
+	"github.com/azeroth-sha/y/ytime"
+)
+
+// Rotate is the time boundary a FileWriter rotates on
+type Rotate uint8
+
+const (
+	RotateNone Rotate = iota // 不按时间切割
+	RotateHour               // 整点切割
+	RotateDay                // 零点切割
+)
+
+// BackupTime is the time layout in backup names, e.g. app-2006-01-02T15-04-05.000.log
+const BackupTime = `2006-01-02T15-04-05.000`
+
+var ErrFileClosed = errors.New("ylog: file writer closed")
+
+type FileOption func(*FileWriter)
+
+// WithMaxSize set the size in bytes a file rotates at, 0 disables it
+func WithMaxSize(n int64) FileOption {
+	return func(w *FileWriter) {
+		w.maxSize = n
+	}
+}
+
+// WithRotate set the time boundary a file rotates at
+func WithRotate(r Rotate) FileOption {
+	return func(w *FileWriter) {
+		w.rotate = r
+	}
+}
+
+// WithMaxBackups set the number of rotated files to keep, 0 keeps all
+func WithMaxBackups(n int) FileOption {
+	return func(w *FileWriter) {
+		w.backups = n
+	}
+}
+
+// WithCompress set whether rotated files are gzipped
+func WithCompress(enable bool) FileOption {
+	return func(w *FileWriter) {
+		w.compress = enable
+	}
+}
+
+// FileWriter is an io.WriteCloser appending to a file that rotates by size
+// and time, rotated files are renamed with the rotation time, see BackupTime.
+// It is safe for concurrent use.
+type FileWriter struct {
+	mu       *sync.Mutex
+	name     string
+	maxSize  int64
+	rotate   Rotate
+	backups  int
+	compress bool
+	file     *os.File
+	size     int64
+	period   time.Time // begin of the time boundary of the current file
+	mill     chan struct{}
+	wait     *sync.WaitGroup
+	closed   bool
+}
+
+func (w *FileWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrFileClosed
+	}
+	if w.file != nil && w.due(time.Now(), int64(len(p))) {
+		if err = w.rotateFile(); err != nil {
+			return 0, err
+		}
+	}
+	if w.file == nil {
+		if err = w.openFile(); err != nil {
+			return 0, err
+		}
+	}
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file and renames it as a backup, the next write opens a new one.
+func (w *FileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrFileClosed
+	}
+	if w.file == nil {
+		return nil
+	}
+	return w.rotateFile()
+}
+
+// Sync commits the current file to disk.
+func (w *FileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close closes the file and waits for the pending compression and cleanup.
+func (w *FileWriter) Close() (err error) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	close(w.mill)
+	w.mu.Unlock()
+	w.wait.Wait()
+	return err
+}
+
+// NewFileWriter returns a FileWriter appending to name, missing directories are created.
+func NewFileWriter(name string, opts ...FileOption) (*FileWriter, error) {
+	w := &FileWriter{
+		mu:   new(sync.Mutex),
+		name: name,
+		mill: make(chan struct{}, 1),
+		wait: new(sync.WaitGroup),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if err := w.openFile(); err != nil {
+		return nil, err
+	}
+	w.wait.Add(1)
+	go w.milling()
+	return w, nil
+}
+
+/*
+  Package private
+*/
+
+// due reports whether writing n more bytes at now needs a new file.
+func (w *FileWriter) due(now time.Time, n int64) bool {
+	if w.maxSize > 0 && w.size > 0 && w.size+n > w.maxSize {
+		return true
+	}
+	return w.rotate != RotateNone && !w.begin(now).Equal(w.period)
+}
+
+func (w *FileWriter) begin(t time.Time) time.Time {
+	switch w.rotate {
+	case RotateHour:
+		return ytime.BeginHour(t)
+	case RotateDay:
+		return ytime.BeginDay(t)
+	default:
+		return time.Time{}
+	}
+}
+
+func (w *FileWriter) openFile() error {
+	if err := os.MkdirAll(filepath.Dir(w.name), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.file, w.size = f, fi.Size()
+	w.period = w.begin(time.Now())
+	if w.size > 0 {
+		// an existing file belongs to the boundary it was last written in
+		w.period = w.begin(fi.ModTime())
+	}
+	return nil
+}
+
+func (w *FileWriter) rotateFile() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	base, ext := w.split()
+	stamp := time.Now()
+	dst := base + stamp.Format(BackupTime) + ext
+	for yfile.IsExist(dst) || yfile.IsExist(dst+`.gz`) {
+		stamp = stamp.Add(time.Millisecond)
+		dst = base + stamp.Format(BackupTime) + ext
+	}
+	if err := os.Rename(w.name, dst); err != nil {
+		return err
+	}
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+	return w.openFile()
+}
+
+// split returns the backup name prefix and extension, "dir/app-" and ".log" for "dir/app.log".
+func (w *FileWriter) split() (base, ext string) {
+	ext = filepath.Ext(w.name)
+	return strings.TrimSuffix(w.name, ext) + `-`, ext
+}
+
+// milling compresses and removes backups after each rotation until Close.
+func (w *FileWriter) milling() {
+	defer w.wait.Done()
+	for range w.mill {
+		w.millOnce()
+	}
+}
+
+func (w *FileWriter) millOnce() {
+	files := w.backupFiles()
+	if w.backups > 0 && len(files) > w.backups {
+		for _, f := range files[:len(files)-w.backups] {
+			_ = os.Remove(f)
+		}
+		files = files[len(files)-w.backups:]
+	}
+	if !w.compress {
+		return
+	}
+	for _, f := range files {
+		if !strings.HasSuffix(f, `.gz`) {
+			_ = gzipFile(f)
+		}
+	}
+}
+
+// backupFiles returns the rotated files of the writer, oldest first.
+func (w *FileWriter) backupFiles() (files []string) {
+	base, ext := w.split()
+	entries, err := os.ReadDir(filepath.Dir(w.name))
+	if err != nil {
+		return nil
+	}
+	prefix := filepath.Base(base)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name[len(prefix):], `.gz`), ext)
+		if _, err := time.Parse(BackupTime, stamp); err != nil {
+			continue
+		}
+		files = append(files, filepath.Join(filepath.Dir(w.name), name))
+	}
+	slices.SortFunc(files, func(a, b string) int {
+		return strings.Compare(strings.TrimSuffix(a, `.gz`), strings.TrimSuffix(b, `.gz`))
+	})
+	return files
+}
+
+func gzipFile(name string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+	dst, err := os.OpenFile(name+`.gz`, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if e := dst.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		_ = os.Remove(name + `.gz`)
+		return err
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,201 @@
+package ylog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// readLogs returns the backups of w oldest first, then the current file.
+func readLogs(t *testing.T, w *FileWriter) (files []string, contents []string) {
+	t.Helper()
+	files = append(w.backupFiles(), w.name)
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasSuffix(name, `.gz`) {
+			zr, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if data, err = io.ReadAll(zr); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+		contents = append(contents, string(data))
+	}
+	return files, contents
+}
+
+func write(t *testing.T, w *FileWriter, s string) {
+	t.Helper()
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileWriterSize(t *testing.T) {
+	w, err := NewFileWriter(filepath.Join(t.TempDir(), `sub`, `app.log`), WithMaxSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, w, "aaaa\n")
+	write(t, w, "bbbb\n")
+	write(t, w, "cccc\n")             // 15 bytes, rotates
+	write(t, w, "0123456789abcdef\n") // larger than the limit, rotates once
+	write(t, w, "dddd\n")
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, got := readLogs(t, w)
+	want := []string{"aaaa\nbbbb\n", "cccc\n", "0123456789abcdef\n", "dddd\n"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("files %v hold %q, want %q", files, got, want)
+	}
+	if _, err = w.Write([]byte("x")); err != ErrFileClosed {
+		t.Errorf("Write after Close = %v, want ErrFileClosed", err)
+	}
+}
+
+func TestFileWriterBoundary(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		rotate Rotate
+		period time.Duration
+	}{
+		{`hour`, RotateHour, time.Hour},
+		{`day`, RotateDay, 24 * time.Hour},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewFileWriter(filepath.Join(t.TempDir(), `app.log`), WithRotate(tt.rotate))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			now := time.Now()
+			begin := w.begin(now)
+			if !w.period.Equal(begin) {
+				t.Fatalf("period = %v, want %v", w.period, begin)
+			}
+			if w.due(begin.Add(tt.period-time.Nanosecond), 1) {
+				t.Error("due before the boundary")
+			}
+			if !w.due(begin.Add(tt.period), 1) {
+				t.Error("not due at the boundary")
+			}
+			write(t, w, "old\n")
+			w.period = begin.Add(-tt.period) // as if written in the last period
+			write(t, w, "new\n")
+			write(t, w, "new\n")
+			if _, got := readLogs(t, w); fmt.Sprint(got) != fmt.Sprint([]string{"old\n", "new\nnew\n"}) {
+				t.Errorf("files hold %q", got)
+			}
+		})
+	}
+}
+
+func TestFileWriterReopen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), `app.log`)
+	if err := os.WriteFile(name, []byte("yesterday\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(name, old, old); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewFileWriter(name, WithRotate(RotateDay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, w, "today\n")
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, got := readLogs(t, w); fmt.Sprint(got) != fmt.Sprint([]string{"yesterday\n", "today\n"}) {
+		t.Errorf("files hold %q", got)
+	}
+}
+
+func TestFileWriterBackups(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		t.Run(fmt.Sprint(`compress=`, compress), func(t *testing.T) {
+			w, err := NewFileWriter(filepath.Join(t.TempDir(), `app.log`), WithMaxBackups(2), WithCompress(compress))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 5; i++ {
+				write(t, w, fmt.Sprintln(i))
+				if err = w.Rotate(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write(t, w, "current\n")
+			if err = w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			files, got := readLogs(t, w)
+			if want := []string{"3\n", "4\n", "current\n"}; fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("files %v hold %q, want %q", files, got, want)
+			}
+			for _, f := range files[:len(files)-1] {
+				if gz := strings.HasSuffix(f, `.gz`); gz != compress {
+					t.Errorf("backup %s, want compressed %v", f, compress)
+				}
+			}
+		})
+	}
+}
+
+func TestFileWriterConcurrent(t *testing.T) {
+	const writers, lines = 8, 200
+	w, err := NewFileWriter(filepath.Join(t.TempDir(), `app.log`), WithMaxSize(1024), WithCompress(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait := new(sync.WaitGroup)
+	for i := 0; i < writers; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			for j := 0; j < lines; j++ {
+				if _, err := fmt.Fprintf(w, "writer=%d line=%03d\n", i, j); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wait.Wait()
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, got := readLogs(t, w)
+	seen := make(map[string]bool)
+	for n, content := range got {
+		if len(content) > 1024 {
+			t.Errorf("%s holds %d bytes, over the max size", files[n], len(content))
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+			if line == "" {
+				continue
+			}
+			var i, j int
+			if _, err := fmt.Sscanf(line, "writer=%d line=%d", &i, &j); err != nil || seen[line] {
+				t.Fatalf("bad or repeated line %q", line)
+			}
+			seen[line] = true
+		}
+	}
+	if len(seen) != writers*lines {
+		t.Errorf("%d lines, want %d", len(seen), writers*lines)
+	}
+}
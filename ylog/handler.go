@@ -0,0 +1,66 @@
+package ylog
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// LevelState is the body of the level handler, Named holds the overrides by logger name
+type LevelState struct {
+	Level Level            `json:"level"`
+	Named map[string]Level `json:"named,omitempty"`
+}
+
+// NewLevelHandler returns an http.Handler reading and changing the levels.
+//
+//	GET  returns {"level":"Info","named":{"ycache":"Debug"}}
+//	PUT  {"level":"Debug"} sets v, {"name":"ycache","level":"Debug"} overrides
+//	     the level of the loggers named ycache and ycache.*, an empty level removes it
+//
+// Both answer with the current state.
+func NewLevelHandler(v *LevelVar) http.Handler {
+	return &levelHandler{v: v}
+}
+
+/*
+  Package private
+*/
+
+type levelHandler struct {
+	v *LevelVar
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Name  string `json:"name"`
+			Level string `json:"level"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Name != "" && req.Level == "" {
+			DelNamedLevel(req.Name)
+			break
+		}
+		l, err := ParseLevel(req.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Name != "" {
+			SetNamedLevel(req.Name, l)
+		} else {
+			h.v.Set(l)
+		}
+	default:
+		w.Header().Set(`Allow`, `GET, PUT`)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set(`Content-Type`, `application/json`)
+	_ = json.NewEncoder(w).Encode(LevelState{Level: h.v.Level(), Named: NamedLevels()})
+}
@@ -0,0 +1,76 @@
+package ylog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveLevel sends a request to h and returns the code and the decoded state.
+func serveLevel(t *testing.T, h http.Handler, method, body string) (int, LevelState) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, `/level`, strings.NewReader(body)))
+	var state LevelState
+	if w.Code == http.StatusOK {
+		if ct := w.Header().Get(`Content-Type`); ct != `application/json` {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+			t.Fatalf("body %q: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code, state
+}
+
+func TestLevelHandler(t *testing.T) {
+	defer DelNamedLevel(`handler`)
+	v := NewLevelVar(LevelInfo)
+	h := NewLevelHandler(v)
+
+	code, state := serveLevel(t, h, http.MethodGet, ``)
+	if code != http.StatusOK || state.Level != LevelInfo || state.Named[`handler`] != 0 {
+		t.Fatalf("GET = %d, %+v, want 200 and Info", code, state)
+	}
+
+	code, state = serveLevel(t, h, http.MethodPut, `{"level":"debug"}`)
+	if code != http.StatusOK || state.Level != LevelDebug || v.Level() != LevelDebug {
+		t.Fatalf("PUT level = %d, %+v, level %v, want 200 and Debug", code, state, v.Level())
+	}
+
+	code, state = serveLevel(t, h, http.MethodPut, `{"name":"handler","level":"Error"}`)
+	if code != http.StatusOK || state.Named[`handler`] != LevelError || state.Level != LevelDebug {
+		t.Fatalf("PUT named = %d, %+v, want 200 and handler Error", code, state)
+	}
+	if l, ok := NamedLevels()[`handler`]; !ok || l != LevelError {
+		t.Errorf("NamedLevels()[handler] = %v, %v, want Error, true", l, ok)
+	}
+
+	code, state = serveLevel(t, h, http.MethodPut, `{"name":"handler"}`)
+	if _, ok := state.Named[`handler`]; code != http.StatusOK || ok {
+		t.Fatalf("PUT empty level = %d, %+v, want 200 and no handler override", code, state)
+	}
+	if _, ok := NamedLevels()[`handler`]; ok {
+		t.Error("override of handler kept after its removal")
+	}
+
+	for _, body := range []string{`{"level":`, `not json`, `{"level":"loud"}`, `{"name":"handler","level":"loud"}`, `{}`} {
+		if code, _ = serveLevel(t, h, http.MethodPut, body); code != http.StatusBadRequest {
+			t.Errorf("PUT %s = %d, want 400", body, code)
+		}
+	}
+	if v.Level() != LevelDebug {
+		t.Errorf("level = %v after bad requests, want Debug", v.Level())
+	}
+	if _, ok := NamedLevels()[`handler`]; ok {
+		t.Error("bad request set an override of handler")
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, `/level`, nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get(`Allow`) != `GET, PUT` {
+		t.Errorf("POST = %d, Allow %q, want 405 and GET, PUT", w.Code, w.Header().Get(`Allow`))
+	}
+}
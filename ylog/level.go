@@ -0,0 +1,127 @@
+package ylog
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrLevel = errors.New("ylog: unknown level")
+
+// ParseLevel returns the level named s, case-insensitive
+func ParseLevel(s string) (Level, error) {
+	for l := LevelError; l <= LevelFatal; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return 0, ErrLevel
+}
+
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *Level) UnmarshalText(text []byte) (err error) {
+	*l, err = ParseLevel(string(text))
+	return err
+}
+
+// LevelVar is a Level safe for concurrent use, see WithLevelVar
+type LevelVar struct {
+	v atomic.Uint32
+}
+
+// NewLevelVar returns a LevelVar set to l
+func NewLevelVar(l Level) *LevelVar {
+	v := new(LevelVar)
+	v.Set(l)
+	return v
+}
+
+func (v *LevelVar) Level() Level {
+	return Level(v.v.Load())
+}
+
+func (v *LevelVar) Set(l Level) {
+	v.v.Store(uint32(l))
+}
+
+func (v *LevelVar) String() string {
+	return v.Level().String()
+}
+
+// Named returns a child of l named name, see SetNamedLevel. Loggers without
+// a Named method get the name as the "logger" field.
+func Named(l Logger, name string) Logger {
+	if n, ok := l.(interface{ Named(string) Logger }); ok {
+		return n.Named(name)
+	}
+	return l.With(`logger`, name)
+}
+
+// SetNamedLevel overrides the level of the loggers named name, and of their
+// children named "name.child" without an override of their own
+func SetNamedLevel(name string, l Level) {
+	namedVar(name).Set(l)
+}
+
+// DelNamedLevel removes the level override of the loggers named name
+func DelNamedLevel(name string) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	if v, ok := named[name]; ok {
+		v.Set(0)
+	}
+}
+
+// NamedLevels returns the level overrides by logger name
+func NamedLevels() map[string]Level {
+	namedMu.RLock()
+	defer namedMu.RUnlock()
+	levels := make(map[string]Level)
+	for name, v := range named {
+		if l := v.Level(); l != 0 {
+			levels[name] = l
+		}
+	}
+	return levels
+}
+
+/*
+  Package private
+*/
+
+var (
+	namedMu = new(sync.RWMutex)
+	named   = make(map[string]*LevelVar) // 0 means no override
+)
+
+// namedVars returns the overrides of name and its dotted parents, nearest
+// first, e.g. "a.b.c", "a.b" and "a".
+func namedVars(name string) []*LevelVar {
+	vars := []*LevelVar{namedVar(name)}
+	for i := strings.LastIndexByte(name, '.'); i > 0; i = strings.LastIndexByte(name, '.') {
+		name = name[:i]
+		vars = append(vars, namedVar(name))
+	}
+	return vars
+}
+
+// namedVar returns the override of name, loggers keep it so later changes apply.
+func namedVar(name string) *LevelVar {
+	namedMu.RLock()
+	v, ok := named[name]
+	namedMu.RUnlock()
+	if ok {
+		return v
+	}
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	if v, ok = named[name]; !ok {
+		v = new(LevelVar)
+		named[name] = v
+	}
+	return v
+}
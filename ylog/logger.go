@@ -5,6 +5,10 @@ type Level uint8
 
 func (l Level) String() string {
 	switch l {
+	case LevelFatal:
+		return `Fatal`
+	case LevelPanic:
+		return `Panic`
 	case LevelError:
 		return `Error`
 	case LevelWarn:
@@ -18,11 +22,15 @@ func (l Level) String() string {
 	}
 }
 
+// Levels keep their values, Panic and Fatal come after Debug but are the most
+// severe ones: a logger of level l writes the lines at least as severe as l.
 const (
 	LevelError Level = iota + 1
 	LevelWarn
 	LevelInfo
 	LevelDebug
+	LevelPanic
+	LevelFatal
 )
 
 type Logger interface {
@@ -34,6 +42,25 @@ type Logger interface {
 	Warnf(format string, args ...any)
 	Error(args ...any)
 	Errorf(format string, args ...any)
+	// Debugw logs msg with key-value pairs, so do Infow, Warnw and Errorw.
+	Debugw(msg string, kv ...any)
+	Infow(msg string, kv ...any)
+	Warnw(msg string, kv ...any)
+	Errorw(msg string, kv ...any)
+	// Panic logs then panics with the message, so do Panicf and Panicw.
+	Panic(args ...any)
+	Panicf(format string, args ...any)
+	Panicw(msg string, kv ...any)
+	// Fatal logs, syncs then exits with status 1, so do Fatalf and Fatalw.
+	Fatal(args ...any)
+	Fatalf(format string, args ...any)
+	Fatalw(msg string, kv ...any)
+	// Log logs msg with key-value pairs at level l, it neither panics nor exits.
+	Log(l Level, msg string, kv ...any)
+	// With returns a child logger writing the key-value pairs on each line.
+	With(kv ...any) Logger
+	// Sync flushes the buffered lines.
+	Sync() error
 }
 
 var entry Logger
@@ -51,3 +78,38 @@ func DefaultLog() Logger {
 func SetDefault(v Logger) {
 	entry = v
 }
+
+// AddCallerSkip returns a child of l reporting the caller n more frames up,
+// l is returned as is if it has no AddCallerSkip method.
+func AddCallerSkip(l Logger, n int) Logger {
+	if c, ok := l.(interface{ AddCallerSkip(int) Logger }); ok {
+		return c.AddCallerSkip(n)
+	}
+	return l
+}
+
+/*
+  Package private
+*/
+
+// rank orders the levels by severity, the lower the more severe.
+func (l Level) rank() int {
+	switch l {
+	case LevelFatal:
+		return 1
+	case LevelPanic:
+		return 2
+	case 0:
+		return 0
+	default:
+		return int(l) + 2
+	}
+}
+
+// enabled returns whether log writes lines of level l, true if it can not tell.
+func enabled(log Logger, l Level) bool {
+	if e, ok := log.(interface{ Enabled(Level) bool }); ok {
+		return e.Enabled(l)
+	}
+	return true
+}
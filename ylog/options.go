@@ -8,20 +8,46 @@ type Option func(*Entry)
 
 func WithLevel(level Level) Option {
 	return func(e *Entry) {
-		e.level = level
+		e.level = NewLevelVar(level)
 	}
 }
 
+// WithLevelVar set a level handle that can be changed at runtime, it may be shared by loggers
+func WithLevelVar(v *LevelVar) Option {
+	return func(e *Entry) {
+		if v != nil {
+			e.level = v
+		}
+	}
+}
+
+// WithFile set whether the caller file and line are written, depth skips
+// that many more frames, see WithCallerSkip
 func WithFile(enable, line bool, depth ...int) Option {
 	return func(e *Entry) {
 		e.outFile = enable
 		e.fileLin = line
-		if len(depth) > 1 {
-			e.fileDep = depth[1]
+		if len(depth) > 0 {
+			e.skip = depth[0]
 		}
 	}
 }
 
+// WithCallerSkip set the number of frames skipped above the caller of the
+// logging methods, for loggers called by wrappers, see AddCallerSkip
+func WithCallerSkip(n int) Option {
+	return func(e *Entry) {
+		e.skip = n
+	}
+}
+
+// WithStack set lines of level l or more severe to carry a stack trace
+func WithStack(l Level) Option {
+	return func(e *Entry) {
+		e.stackLv = l
+	}
+}
+
 func WithTime(enable bool, format ...string) Option {
 	return func(e *Entry) {
 		e.outTime = enable
@@ -36,3 +62,20 @@ func WithWriter(writer io.WriteCloser) Option {
 		e.output = writer
 	}
 }
+
+func WithEncoder(enc Encoder) Option {
+	return func(e *Entry) {
+		if enc != nil {
+			e.encoder = enc
+		}
+	}
+}
+
+// WithAsync writes lines from a queue of size in background, a full queue
+// blocks the caller unless drop is true, see Entry.Sync and Entry.Close.
+func WithAsync(size int, drop bool) Option {
+	return func(e *Entry) {
+		e.queue = size
+		e.drop = drop
+	}
+}
@@ -0,0 +1,259 @@
+package ylog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Sampler is a Logger passing the first lines with the same level and format
+// in each interval, then every thereafter-th one, it reports the suppressed
+// counts by a Warn line per format at the end of the interval.
+type Sampler interface {
+	Logger
+	// Close stops the sampler after reporting the suppressed counts.
+	Close() error
+}
+
+// NewSampler returns a Sampler of l, thereafter <= 0 suppresses all lines after the first ones.
+// The key of a line is its format for the f methods, its message for the w ones
+// and the formatted arguments for the others.
+func NewSampler(l Logger, interval time.Duration, first, thereafter int) Sampler {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	st := &sampleState{
+		log:        l,
+		first:      first,
+		thereafter: thereafter,
+		counts:     make(map[sampleKey]*sampleCount),
+		closed:     make(chan struct{}),
+	}
+	st.wait.Add(1)
+	go st.ticking(interval)
+	return &sampler{log: AddCallerSkip(l, 1), st: st}
+}
+
+/*
+  Package private
+*/
+
+type sampleKey struct {
+	level  Level
+	format string
+}
+
+type sampleCount struct {
+	seen       int
+	suppressed int
+}
+
+type sampleState struct {
+	mu         sync.Mutex
+	log        Logger
+	first      int
+	thereafter int
+	counts     map[sampleKey]*sampleCount
+	closed     chan struct{}
+	once       sync.Once
+	wait       sync.WaitGroup
+}
+
+// allow counts the line and reports whether it passes.
+func (st *sampleState) allow(l Level, format string) bool {
+	key := sampleKey{level: l, format: format}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	c, ok := st.counts[key]
+	if !ok {
+		c = new(sampleCount)
+		st.counts[key] = c
+	}
+	c.seen++
+	if c.seen <= st.first || (st.thereafter > 0 && (c.seen-st.first)%st.thereafter == 0) {
+		return true
+	}
+	c.suppressed++
+	return false
+}
+
+// report writes the suppressed counts of the interval and starts a new one.
+func (st *sampleState) report() {
+	st.mu.Lock()
+	counts := st.counts
+	st.counts = make(map[sampleKey]*sampleCount, len(counts))
+	st.mu.Unlock()
+	for key, c := range counts {
+		if c.suppressed > 0 {
+			st.log.Warnw(`ylog: lines suppressed`,
+				`sample_level`, key.level, `sample_format`, key.format, `suppressed`, c.suppressed)
+		}
+	}
+}
+
+func (st *sampleState) ticking(interval time.Duration) {
+	defer st.wait.Done()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			st.report()
+		case <-st.closed:
+			st.report()
+			return
+		}
+	}
+}
+
+type sampler struct {
+	log Logger
+	st  *sampleState
+}
+
+func (s *sampler) Debug(args ...any) {
+	if enabled(s.log, LevelDebug) {
+		if msg := fmt.Sprint(args...); s.st.allow(LevelDebug, msg) {
+			s.log.Debug(msg)
+		}
+	}
+}
+
+func (s *sampler) Debugf(format string, args ...any) {
+	if s.pass(LevelDebug, format) {
+		s.log.Debugf(format, args...)
+	}
+}
+
+func (s *sampler) Debugw(msg string, kv ...any) {
+	if s.pass(LevelDebug, msg) {
+		s.log.Debugw(msg, kv...)
+	}
+}
+
+func (s *sampler) Info(args ...any) {
+	if enabled(s.log, LevelInfo) {
+		if msg := fmt.Sprint(args...); s.st.allow(LevelInfo, msg) {
+			s.log.Info(msg)
+		}
+	}
+}
+
+func (s *sampler) Infof(format string, args ...any) {
+	if s.pass(LevelInfo, format) {
+		s.log.Infof(format, args...)
+	}
+}
+
+func (s *sampler) Infow(msg string, kv ...any) {
+	if s.pass(LevelInfo, msg) {
+		s.log.Infow(msg, kv...)
+	}
+}
+
+func (s *sampler) Warn(args ...any) {
+	if enabled(s.log, LevelWarn) {
+		if msg := fmt.Sprint(args...); s.st.allow(LevelWarn, msg) {
+			s.log.Warn(msg)
+		}
+	}
+}
+
+func (s *sampler) Warnf(format string, args ...any) {
+	if s.pass(LevelWarn, format) {
+		s.log.Warnf(format, args...)
+	}
+}
+
+func (s *sampler) Warnw(msg string, kv ...any) {
+	if s.pass(LevelWarn, msg) {
+		s.log.Warnw(msg, kv...)
+	}
+}
+
+func (s *sampler) Error(args ...any) {
+	if enabled(s.log, LevelError) {
+		if msg := fmt.Sprint(args...); s.st.allow(LevelError, msg) {
+			s.log.Error(msg)
+		}
+	}
+}
+
+func (s *sampler) Errorf(format string, args ...any) {
+	if s.pass(LevelError, format) {
+		s.log.Errorf(format, args...)
+	}
+}
+
+func (s *sampler) Errorw(msg string, kv ...any) {
+	if s.pass(LevelError, msg) {
+		s.log.Errorw(msg, kv...)
+	}
+}
+
+// Panic is not sampled, so are Panicf and Panicw.
+func (s *sampler) Panic(args ...any) {
+	s.log.Panic(args...)
+}
+
+func (s *sampler) Panicf(format string, args ...any) {
+	s.log.Panicf(format, args...)
+}
+
+func (s *sampler) Panicw(msg string, kv ...any) {
+	s.log.Panicw(msg, kv...)
+}
+
+// Fatal is not sampled, so are Fatalf and Fatalw.
+func (s *sampler) Fatal(args ...any) {
+	s.log.Fatal(args...)
+}
+
+func (s *sampler) Fatalf(format string, args ...any) {
+	s.log.Fatalf(format, args...)
+}
+
+func (s *sampler) Fatalw(msg string, kv ...any) {
+	s.log.Fatalw(msg, kv...)
+}
+
+func (s *sampler) Log(l Level, msg string, kv ...any) {
+	if s.pass(l, msg) {
+		s.log.Log(l, msg, kv...)
+	}
+}
+
+// pass reports whether the line is enabled and sampled.
+func (s *sampler) pass(l Level, key string) bool {
+	return enabled(s.log, l) && s.st.allow(l, key)
+}
+
+// With returns a child sharing the counts of s.
+func (s *sampler) With(kv ...any) Logger {
+	return &sampler{log: s.log.With(kv...), st: s.st}
+}
+
+// Named returns a child sharing the counts of s.
+func (s *sampler) Named(name string) Logger {
+	return &sampler{log: Named(s.log, name), st: s.st}
+}
+
+func (s *sampler) AddCallerSkip(n int) Logger {
+	return &sampler{log: AddCallerSkip(s.log, n), st: s.st}
+}
+
+func (s *sampler) Enabled(l Level) bool {
+	return enabled(s.log, l)
+}
+
+func (s *sampler) Sync() error {
+	return s.log.Sync()
+}
+
+func (s *sampler) Close() error {
+	s.st.once.Do(func() {
+		close(s.st.closed)
+	})
+	s.st.wait.Wait()
+	return nil
+}
@@ -0,0 +1,179 @@
+package ylog
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/azeroth-sha/y/ybuff"
+)
+
+// Syncer is implemented by outputs able to commit written data, e.g. *os.File and *FileWriter
+type Syncer interface {
+	Sync() error
+}
+
+// LevelWriter is implemented by outputs using the level of the lines, e.g. *SyslogWriter
+type LevelWriter interface {
+	WriteLevel(l Level, p []byte) (n int, err error)
+}
+
+/*
+  Package private
+*/
+
+// sink serializes the writes of a logger and its children to the output,
+// in async mode through a bounded queue drained by one goroutine.
+type sink struct {
+	mu      *sync.Mutex // guards output writes in sync mode
+	qmu     *sync.RWMutex
+	output  io.WriteCloser
+	queue   chan task
+	drop    bool
+	closed  bool // queue closed, guarded by qmu
+	shut    bool // output closed, guarded by mu
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+	wait    *sync.WaitGroup
+}
+
+// task is a line to write, or a flush request if done is not nil.
+type task struct {
+	level Level
+	buf   *bytes.Buffer
+	done  chan struct{}
+}
+
+// write takes the ownership of buf.
+func (s *sink) write(l Level, buf *bytes.Buffer) {
+	if s.queue == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.shut {
+			s.dropped.Add(1)
+			ybuff.Put(buf)
+		} else {
+			s.put(l, buf)
+		}
+		return
+	}
+	s.qmu.RLock()
+	defer s.qmu.RUnlock()
+	if s.closed {
+		s.dropped.Add(1)
+		ybuff.Put(buf)
+		return
+	}
+	if !s.drop {
+		s.queue <- task{level: l, buf: buf}
+		return
+	}
+	select {
+	case s.queue <- task{level: l, buf: buf}:
+	default:
+		s.dropped.Add(1)
+		ybuff.Put(buf)
+	}
+}
+
+// Sync waits for the queued lines to be written, then syncs the output.
+func (s *sink) Sync() error {
+	if s.queue != nil {
+		done := make(chan struct{})
+		s.qmu.RLock()
+		if s.closed {
+			s.qmu.RUnlock()
+			return nil
+		}
+		s.queue <- task{done: done}
+		s.qmu.RUnlock()
+		<-done
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shut {
+		return nil
+	}
+	if o, ok := s.output.(Syncer); ok {
+		return o.Sync()
+	}
+	return nil
+}
+
+// Close writes the queued lines and closes the output, later lines are dropped.
+func (s *sink) Close() error {
+	if s.queue != nil {
+		s.qmu.Lock()
+		if s.closed {
+			s.qmu.Unlock()
+			return nil
+		}
+		s.closed = true
+		close(s.queue)
+		s.qmu.Unlock()
+		s.wait.Wait()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shut {
+		return nil
+	}
+	s.shut = true
+	var err error
+	if o, ok := s.output.(Syncer); ok {
+		err = o.Sync()
+	}
+	if e := s.output.Close(); e != nil {
+		err = e
+	}
+	return err
+}
+
+func (s *sink) drain() {
+	defer s.wait.Done()
+	for t := range s.queue {
+		if t.buf != nil {
+			s.put(t.level, t.buf)
+		}
+		if t.done != nil {
+			close(t.done)
+		}
+	}
+}
+
+// put writes buf to the output and releases it, a failed or panicking write is counted.
+func (s *sink) put(l Level, buf *bytes.Buffer) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			s.failed.Add(1)
+		}
+		ybuff.Put(buf)
+	}()
+	var err error
+	if w, ok := s.output.(LevelWriter); ok {
+		_, err = w.WriteLevel(l, buf.Bytes())
+	} else {
+		_, err = buf.WriteTo(s.output)
+	}
+	if err != nil {
+		s.failed.Add(1)
+	}
+}
+
+// newSink returns a sink of output, size > 0 enables the async mode.
+func newSink(output io.WriteCloser, size int, drop bool) *sink {
+	s := &sink{
+		mu:     new(sync.Mutex),
+		qmu:    new(sync.RWMutex),
+		output: output,
+		drop:   drop,
+		wait:   new(sync.WaitGroup),
+	}
+	if size > 0 {
+		s.queue = make(chan task, size)
+		s.wait.Add(1)
+		go s.drain()
+	}
+	return s
+}
@@ -0,0 +1,308 @@
+package ylog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+)
+
+// SlogLevel returns the slog level of l
+func SlogLevel(l Level) slog.Level {
+	switch l {
+	case LevelFatal:
+		return slogLevelFatal
+	case LevelPanic:
+		return slogLevelPanic
+	case LevelError:
+		return slog.LevelError
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelInfo:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
+
+// FromSlogLevel returns the ylog level of l, levels in between round down to the next one
+func FromSlogLevel(l slog.Level) Level {
+	switch {
+	case l >= slogLevelFatal:
+		return LevelFatal
+	case l >= slogLevelPanic:
+		return LevelPanic
+	case l >= slog.LevelError:
+		return LevelError
+	case l >= slog.LevelWarn:
+		return LevelWarn
+	case l >= slog.LevelInfo:
+		return LevelInfo
+	default:
+		return LevelDebug
+	}
+}
+
+// NewSlogHandler returns a slog.Handler writing the records through l,
+// attributes become key-value fields and groups prefix their keys with "group.".
+// The fields of the registered extractors for the record context come first.
+// Panic and Fatal records neither panic nor exit.
+func NewSlogHandler(l Logger) slog.Handler {
+	return &slogHandler{log: l}
+}
+
+// FromSlog returns a Logger writing through l.
+func FromSlog(l *slog.Logger) Logger {
+	return &slogLogger{log: l}
+}
+
+/*
+  Package private
+*/
+
+const (
+	slogLevelPanic = slog.LevelError + 4
+	slogLevelFatal = slog.LevelError + 8
+)
+
+type slogHandler struct {
+	log    Logger
+	prefix string // joined group names followed by a dot
+}
+
+func (h *slogHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return enabled(h.log, FromSlogLevel(l))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	kv := make([]any, 0, r.NumAttrs()*2)
+	if ctx != nil {
+		kv = append(kv, extract(ctx)...)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		kv = appendAttr(kv, h.prefix, a)
+		return true
+	})
+	l := FromSlogLevel(r.Level)
+	if e, ok := h.log.(*Entry); ok && r.PC != 0 {
+		if e.Enabled(l) {
+			var stack []uintptr
+			if e.stacked(l) {
+				var pcs [maxStack]uintptr
+				stack = pcs[:runtime.Callers(2, pcs[:])]
+			}
+			e.emitPC(r.PC, stack, l, r.Message, kv)
+		}
+		return nil
+	}
+	AddCallerSkip(h.log, h.skip(r.PC)).Log(l, r.Message, kv...)
+	return nil
+}
+
+// skip returns the frames from Handle up to the source of the record, the
+// caller of Handle need not be a slog.Logger, e.g. FromSlog or a wrapper.
+func (h *slogHandler) skip(pc uintptr) int {
+	if pc != 0 {
+		var pcs [maxStack]uintptr
+		for i, p := range pcs[:runtime.Callers(2, pcs[:])] {
+			if p == pc {
+				return i
+			}
+		}
+	}
+	return 3 // Handle, slog.Logger.log and the slog.Logger method
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	kv := make([]any, 0, len(attrs)*2)
+	for _, a := range attrs {
+		kv = appendAttr(kv, h.prefix, a)
+	}
+	return &slogHandler{log: h.log.With(kv...), prefix: h.prefix}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{log: h.log, prefix: h.prefix + name + `.`}
+}
+
+// appendAttr appends a as key-value pairs, flattening groups.
+func appendAttr(kv []any, prefix string, a slog.Attr) []any {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + `.`
+		}
+		for _, g := range a.Value.Group() {
+			kv = appendAttr(kv, prefix, g)
+		}
+		return kv
+	}
+	if a.Key == "" {
+		return kv
+	}
+	return append(kv, prefix+a.Key, a.Value.Any())
+}
+
+type slogLogger struct {
+	log  *slog.Logger
+	skip int
+}
+
+func (s *slogLogger) Debug(args ...any) {
+	if s.enabled(slog.LevelDebug) {
+		s.output(slog.LevelDebug, fmt.Sprint(args...), nil)
+	}
+}
+
+func (s *slogLogger) Debugf(format string, args ...any) {
+	if s.enabled(slog.LevelDebug) {
+		s.output(slog.LevelDebug, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (s *slogLogger) Debugw(msg string, kv ...any) {
+	if s.enabled(slog.LevelDebug) {
+		s.output(slog.LevelDebug, msg, kv)
+	}
+}
+
+func (s *slogLogger) Info(args ...any) {
+	if s.enabled(slog.LevelInfo) {
+		s.output(slog.LevelInfo, fmt.Sprint(args...), nil)
+	}
+}
+
+func (s *slogLogger) Infof(format string, args ...any) {
+	if s.enabled(slog.LevelInfo) {
+		s.output(slog.LevelInfo, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (s *slogLogger) Infow(msg string, kv ...any) {
+	if s.enabled(slog.LevelInfo) {
+		s.output(slog.LevelInfo, msg, kv)
+	}
+}
+
+func (s *slogLogger) Warn(args ...any) {
+	if s.enabled(slog.LevelWarn) {
+		s.output(slog.LevelWarn, fmt.Sprint(args...), nil)
+	}
+}
+
+func (s *slogLogger) Warnf(format string, args ...any) {
+	if s.enabled(slog.LevelWarn) {
+		s.output(slog.LevelWarn, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (s *slogLogger) Warnw(msg string, kv ...any) {
+	if s.enabled(slog.LevelWarn) {
+		s.output(slog.LevelWarn, msg, kv)
+	}
+}
+
+func (s *slogLogger) Error(args ...any) {
+	if s.enabled(slog.LevelError) {
+		s.output(slog.LevelError, fmt.Sprint(args...), nil)
+	}
+}
+
+func (s *slogLogger) Errorf(format string, args ...any) {
+	if s.enabled(slog.LevelError) {
+		s.output(slog.LevelError, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (s *slogLogger) Errorw(msg string, kv ...any) {
+	if s.enabled(slog.LevelError) {
+		s.output(slog.LevelError, msg, kv)
+	}
+}
+
+func (s *slogLogger) Panic(args ...any) {
+	msg := fmt.Sprint(args...)
+	if s.enabled(slogLevelPanic) {
+		s.output(slogLevelPanic, msg, nil)
+	}
+	panic(msg)
+}
+
+func (s *slogLogger) Panicf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if s.enabled(slogLevelPanic) {
+		s.output(slogLevelPanic, msg, nil)
+	}
+	panic(msg)
+}
+
+func (s *slogLogger) Panicw(msg string, kv ...any) {
+	if s.enabled(slogLevelPanic) {
+		s.output(slogLevelPanic, msg, kv)
+	}
+	panic(msg)
+}
+
+func (s *slogLogger) Fatal(args ...any) {
+	if s.enabled(slogLevelFatal) {
+		s.output(slogLevelFatal, fmt.Sprint(args...), nil)
+	}
+	os.Exit(1)
+}
+
+func (s *slogLogger) Fatalf(format string, args ...any) {
+	if s.enabled(slogLevelFatal) {
+		s.output(slogLevelFatal, fmt.Sprintf(format, args...), nil)
+	}
+	os.Exit(1)
+}
+
+func (s *slogLogger) Fatalw(msg string, kv ...any) {
+	if s.enabled(slogLevelFatal) {
+		s.output(slogLevelFatal, msg, kv)
+	}
+	os.Exit(1)
+}
+
+func (s *slogLogger) Log(l Level, msg string, kv ...any) {
+	if lv := SlogLevel(l); s.enabled(lv) {
+		s.output(lv, msg, kv)
+	}
+}
+
+func (s *slogLogger) With(kv ...any) Logger {
+	return &slogLogger{log: s.log.With(kv...), skip: s.skip}
+}
+
+func (s *slogLogger) AddCallerSkip(n int) Logger {
+	return &slogLogger{log: s.log, skip: s.skip + n}
+}
+
+func (s *slogLogger) Sync() error {
+	return nil
+}
+
+func (s *slogLogger) Enabled(l Level) bool {
+	return s.enabled(SlogLevel(l))
+}
+
+func (s *slogLogger) enabled(l slog.Level) bool {
+	return s.log.Handler().Enabled(context.Background(), l)
+}
+
+// output sends the record to the handler, its source is the caller of the Logger method.
+func (s *slogLogger) output(l slog.Level, msg string, kv []any) {
+	var pcs [1]uintptr
+	runtime.Callers(3+s.skip, pcs[:])
+	r := slog.NewRecord(time.Now(), l, msg, pcs[0])
+	r.Add(kv...)
+	_ = s.log.Handler().Handle(context.Background(), r)
+}
@@ -0,0 +1,443 @@
+package ylog
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SyslogFormat is the message framing of a SyslogWriter
+type SyslogFormat uint8
+
+const (
+	RFC5424 SyslogFormat = iota // <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID - - MSG
+	RFC3164                     // <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+)
+
+// Facility is the syslog facility of the messages
+type Facility uint8
+
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+)
+
+const (
+	FacilityLocal0 Facility = iota + 16
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+const (
+	DefaultSyslogBuffer  = 1024
+	DefaultSyslogRetry   = time.Second
+	DefaultSyslogTimeout = 5 * time.Second
+)
+
+var ErrSyslogClosed = errors.New("ylog: syslog writer closed")
+
+// errSyslogWait is returned by flush while waiting for the next connection attempt.
+var errSyslogWait = errors.New("ylog: syslog reconnect pending")
+
+// SyslogSeverity returns the syslog severity of l, Info for unknown levels
+func SyslogSeverity(l Level) int {
+	switch l {
+	case LevelFatal:
+		return 1 // alert
+	case LevelPanic:
+		return 2 // critical
+	case LevelError:
+		return 3 // error
+	case LevelWarn:
+		return 4 // warning
+	case LevelDebug:
+		return 7 // debug
+	default:
+		return 6 // informational
+	}
+}
+
+type SyslogOption func(*SyslogWriter)
+
+// WithSyslogFormat set the message framing, RFC5424 by default
+func WithSyslogFormat(f SyslogFormat) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.format = f
+	}
+}
+
+// WithFacility set the facility, FacilityUser by default
+func WithFacility(f Facility) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.facility = f
+	}
+}
+
+// WithTag set the app name, the program name by default
+func WithTag(tag string) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.tag = tag
+	}
+}
+
+// WithHostname set the host name, os.Hostname by default
+func WithHostname(name string) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.host = name
+	}
+}
+
+// WithSyslogBuffer set the number of messages kept while disconnected, the oldest are dropped
+func WithSyslogBuffer(size int) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.size = size
+	}
+}
+
+// WithSyslogRetry set the least time between two connection attempts
+func WithSyslogRetry(d time.Duration) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.retry = d
+	}
+}
+
+// WithSyslogTimeout set the connect and write timeout
+func WithSyslogTimeout(d time.Duration) SyslogOption {
+	return func(w *SyslogWriter) {
+		w.timeout = d
+	}
+}
+
+// SyslogWriter sends each write as a syslog message to a syslog server, stream
+// connections frame RFC5424 messages by octet counting and RFC3164 ones by a
+// newline. Writes only buffer the message, a background goroutine connects
+// and sends them, so a dead server never blocks the caller, the oldest
+// messages are dropped when the buffer is full. It is safe for concurrent use.
+type SyslogWriter struct {
+	mu       *sync.Mutex // guards pending and closed
+	cmu      *sync.Mutex // guards the connection, held while sending
+	network  string
+	proto    string // network of the connection, the local socket may be a stream
+	addr     string
+	format   SyslogFormat
+	facility Facility
+	tag      string
+	host     string
+	pid      string
+	size     int
+	retry    time.Duration
+	timeout  time.Duration
+	conn     net.Conn
+	dialed   time.Time // last connection attempt
+	pending  []syslogMsg
+	dropped  atomic.Uint64
+	closed   bool
+	kick     chan struct{}
+	done     chan struct{}
+	wait     *sync.WaitGroup
+}
+
+// Write sends p with the Info severity.
+func (w *SyslogWriter) Write(p []byte) (n int, err error) {
+	return w.WriteLevel(LevelInfo, p)
+}
+
+// WriteLevel sends p with the severity of l, see SyslogSeverity.
+func (w *SyslogWriter) WriteLevel(l Level, p []byte) (n int, err error) {
+	msg := syslogMsg{level: l, time: time.Now(), text: bytes.Clone(bytes.TrimRight(p, "\r\n"))}
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, ErrSyslogClosed
+	}
+	w.enqueue(msg)
+	w.mu.Unlock()
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Sync connects if needed and sends the buffered messages, it waits for
+// the connection up to the timeout, see WithSyslogTimeout.
+func (w *SyslogWriter) Sync() error {
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+	if closed {
+		return ErrSyslogClosed
+	}
+	return w.flush(true)
+}
+
+// Close sends the buffered messages if possible and closes the connection.
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.done)
+	w.wait.Wait()
+	err := w.flush(true)
+	w.cmu.Lock()
+	defer w.cmu.Unlock()
+	if w.conn != nil {
+		if e := w.conn.Close(); err == nil {
+			err = e
+		}
+		w.conn = nil
+	}
+	return err
+}
+
+// Dropped returns the number of messages dropped by a full buffer or a failed datagram write.
+func (w *SyslogWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// NewSyslogWriter returns a SyslogWriter sending to addr over network, which
+// is "udp", "tcp", "unix" or "unixgram". An empty network connects to the
+// local syslog socket, an unreachable server is retried in the background.
+func NewSyslogWriter(network, addr string, opts ...SyslogOption) (*SyslogWriter, error) {
+	w := &SyslogWriter{
+		mu:       new(sync.Mutex),
+		cmu:      new(sync.Mutex),
+		network:  network,
+		addr:     addr,
+		format:   RFC5424,
+		facility: FacilityUser,
+		tag:      progName(),
+		pid:      strconv.Itoa(os.Getpid()),
+		size:     DefaultSyslogBuffer,
+		retry:    DefaultSyslogRetry,
+		timeout:  DefaultSyslogTimeout,
+		kick:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		wait:     new(sync.WaitGroup),
+	}
+	w.host, _ = os.Hostname()
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.facility > FacilityLocal7 {
+		w.facility = FacilityUser
+	}
+	w.proto = w.network
+	if w.proto == "" {
+		w.proto = `unixgram`
+	}
+	if err := w.connect(); err != nil {
+		// a bad network or address is not worth retrying
+		var netErr net.UnknownNetworkError
+		var addrErr *net.AddrError
+		if errors.As(err, &netErr) || errors.As(err, &addrErr) {
+			return nil, err
+		}
+	}
+	w.wait.Add(1)
+	go w.sending()
+	return w, nil
+}
+
+/*
+  Package private
+*/
+
+// localSockets are the usual paths of the local syslog socket.
+var localSockets = []string{`/dev/log`, `/var/run/syslog`, `/var/run/log`}
+
+// syslogMsg is a buffered message, it is framed when sent for the network
+// of the connection then.
+type syslogMsg struct {
+	level Level
+	time  time.Time
+	text  []byte
+}
+
+// frame returns the message as sent over the connection.
+func (w *SyslogWriter) frame(m syslogMsg) []byte {
+	b := bytes.NewBuffer(make([]byte, 0, len(m.text)+96))
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(int(w.facility)*8 + SyslogSeverity(m.level)))
+	b.WriteByte('>')
+	switch w.format {
+	case RFC3164:
+		b.WriteString(m.time.Format(time.Stamp))
+		if !w.local() {
+			b.WriteByte(' ')
+			b.WriteString(nilValue(w.host))
+		}
+		b.WriteByte(' ')
+		b.WriteString(w.tag)
+		b.WriteByte('[')
+		b.WriteString(w.pid)
+		b.WriteString(`]: `)
+	default:
+		b.WriteString(`1 `)
+		b.WriteString(m.time.Format(`2006-01-02T15:04:05.000000Z07:00`))
+		b.WriteByte(' ')
+		b.WriteString(nilValue(w.host))
+		b.WriteByte(' ')
+		b.WriteString(nilValue(w.tag))
+		b.WriteByte(' ')
+		b.WriteString(w.pid)
+		b.WriteString(` - - `)
+	}
+	b.Write(m.text)
+	if w.stream() {
+		if w.format == RFC3164 {
+			b.WriteByte('\n')
+		} else {
+			msg := b.Bytes()
+			return append(append(strconv.AppendInt(nil, int64(len(msg)), 10), ' '), msg...)
+		}
+	}
+	return b.Bytes()
+}
+
+// enqueue buffers msg, dropping the oldest message if the buffer is full.
+func (w *SyslogWriter) enqueue(msg syslogMsg) {
+	if w.size > 0 && len(w.pending) >= w.size {
+		w.pending = w.pending[1:]
+		w.dropped.Add(1)
+	}
+	w.pending = append(w.pending, msg)
+}
+
+// sending flushes after each write and retries a failed connection until Close.
+func (w *SyslogWriter) sending() {
+	defer w.wait.Done()
+	retry := time.NewTimer(w.retry)
+	retry.Stop()
+	defer retry.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.kick:
+		case <-retry.C:
+		}
+		if err := w.flush(false); err != nil {
+			retry.Reset(w.retry)
+		}
+	}
+}
+
+// flush sends the buffered messages, force ignores the retry interval.
+func (w *SyslogWriter) flush(force bool) error {
+	w.cmu.Lock()
+	defer w.cmu.Unlock()
+	w.mu.Lock()
+	batch := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+	var err error
+	if w.conn == nil {
+		if !force && time.Since(w.dialed) < w.retry {
+			err = errSyslogWait
+		} else {
+			err = w.connect()
+		}
+	}
+	for err == nil && len(batch) > 0 {
+		if w.timeout > 0 {
+			_ = w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+		}
+		if _, err = w.conn.Write(w.frame(batch[0])); err != nil {
+			// a stream may have sent part of the message, it is sent again whole,
+			// a datagram is dropped so that it can not block the others
+			if !w.stream() {
+				batch = batch[1:]
+				w.dropped.Add(1)
+			}
+			_ = w.conn.Close()
+			w.conn = nil
+			break
+		}
+		batch = batch[1:]
+	}
+	if len(batch) > 0 {
+		// put the unsent messages back before the ones written meanwhile
+		w.mu.Lock()
+		batch = append(batch, w.pending...)
+		w.pending = nil
+		for _, m := range batch {
+			w.enqueue(m)
+		}
+		w.mu.Unlock()
+	}
+	return err
+}
+
+// connect dials the server, the local socket is tried as a datagram then a stream.
+func (w *SyslogWriter) connect() (err error) {
+	w.dialed = time.Now()
+	if w.network != "" {
+		w.conn, err = net.DialTimeout(w.network, w.addr, w.timeout)
+		return err
+	}
+	for _, network := range []string{`unixgram`, `unix`} {
+		for _, path := range localSockets {
+			if w.conn, err = net.DialTimeout(network, path, w.timeout); err == nil {
+				w.proto = network
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// stream reports whether the messages need framing.
+func (w *SyslogWriter) stream() bool {
+	return w.proto == `tcp` || w.proto == `tcp4` || w.proto == `tcp6` || w.proto == `unix`
+}
+
+// local reports whether the server is on this host, RFC3164 messages omit the host name then.
+func (w *SyslogWriter) local() bool {
+	return w.network == "" || w.network == `unix` || w.network == `unixgram`
+}
+
+// nilValue returns "-" for an empty RFC5424 header field.
+func nilValue(s string) string {
+	if s == "" {
+		return `-`
+	}
+	return s
+}
+
+func progName() string {
+	name := os.Args[0]
+	for i := len(name) - 1; i >= 0; i-- {
+		if os.IsPathSeparator(name[i]) {
+			return name[i+1:]
+		}
+	}
+	return name
+}
@@ -0,0 +1,206 @@
+package ylog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	rfc5424Re = regexp.MustCompile(`^<(\d+)>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}(Z|[+-]\d\d:\d\d) host app (\d+) - - (.*)$`)
+	rfc3164Re = regexp.MustCompile(`^<(\d+)>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d (?:host )?app\[(\d+)\]: (.*)$`)
+)
+
+func newSyslog(t *testing.T, network, addr string, opts ...SyslogOption) *SyslogWriter {
+	t.Helper()
+	opts = append([]SyslogOption{WithTag(`app`), WithHostname(`host`), WithSyslogRetry(10 * time.Millisecond)}, opts...)
+	w, err := NewSyslogWriter(network, addr, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+	return w
+}
+
+// readPacket returns the next datagram of pc.
+func readPacket(t *testing.T, pc net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 4096)
+	_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+// readOctets returns the next octet counted message of r.
+func readOctets(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	size, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(size))
+	if err != nil {
+		t.Fatalf("bad octet count %q", size)
+	}
+	msg := make([]byte, n)
+	if _, err = io.ReadFull(r, msg); err != nil {
+		t.Fatal(err)
+	}
+	return string(msg)
+}
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket(`udp`, `127.0.0.1:0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	w := newSyslog(t, `udp`, pc.LocalAddr().String(), WithFacility(FacilityLocal0))
+	l := NewLogger(WithWriter(w), WithLevel(LevelDebug), WithTime(false))
+	pid := strconv.Itoa(os.Getpid())
+	for _, tt := range []struct {
+		level    Level
+		severity int
+	}{
+		{LevelFatal, 1},
+		{LevelPanic, 2},
+		{LevelError, 3},
+		{LevelWarn, 4},
+		{LevelInfo, 6},
+		{LevelDebug, 7},
+	} {
+		l.Log(tt.level, `hello`, `k`, 1)
+		got := readPacket(t, pc)
+		m := rfc5424Re.FindStringSubmatch(got)
+		if m == nil {
+			t.Fatalf("%s: not RFC 5424: %q", tt.level, got)
+		}
+		if want := strconv.Itoa(int(FacilityLocal0)*8 + tt.severity); m[1] != want || m[3] != pid {
+			t.Errorf("%s: PRI %s PID %s, want %s %s", tt.level, m[1], m[3], want, pid)
+		}
+		if want := fmt.Sprintf(`level=%s k=1 msg=hello`, tt.level); m[4] != want {
+			t.Errorf("%s: message %q, want %q", tt.level, m[4], want)
+		}
+	}
+
+	w3164 := newSyslog(t, `udp`, pc.LocalAddr().String(), WithSyslogFormat(RFC3164))
+	if _, err = w3164.WriteLevel(LevelWarn, []byte("plain\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	got := readPacket(t, pc)
+	if m := rfc3164Re.FindStringSubmatch(got); m == nil || m[1] != `12` || !strings.Contains(got, ` host `) || m[3] != `plain` {
+		t.Errorf("not RFC 3164 with the host: %q", got)
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen(`tcp`, `127.0.0.1:0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	w := newSyslog(t, `tcp`, addr)
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+	_, _ = w.WriteLevel(LevelError, []byte("first\n"))
+	_, _ = w.WriteLevel(LevelInfo, []byte("multi word, second"))
+	for _, want := range []string{`<11>`, `<14>`} {
+		got := readOctets(t, r)
+		if m := rfc5424Re.FindStringSubmatch(got); m == nil || !strings.HasPrefix(got, want) {
+			t.Errorf("got %q, want RFC 5424 starting with %s", got, want)
+		}
+	}
+
+	// the server goes away, writes are buffered and sent after the reconnect
+	_ = conn.Close()
+	_ = ln.Close()
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		_, _ = w.WriteLevel(LevelInfo, []byte(`down `+strconv.Itoa(i)))
+		if d := time.Since(start); d > 100*time.Millisecond {
+			t.Errorf("write took %v while the server is down", d)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if ln, err = net.Listen(`tcp`, addr); err != nil {
+		t.Skipf("listen again on %s: %v", addr, err)
+	}
+	defer ln.Close()
+	_, _ = w.WriteLevel(LevelInfo, []byte(`up`))
+	_ = ln.(*net.TCPListener).SetDeadline(time.Now().Add(2 * time.Second))
+	if conn, err = ln.Accept(); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	r = bufio.NewReader(conn)
+	var got []string
+	for len(got) == 0 || !strings.HasSuffix(got[len(got)-1], ` up`) {
+		got = append(got, readOctets(t, r))
+	}
+	// the write failing on the closed connection may be lost, not the later ones
+	if n := len(got); n < 3 || !strings.HasSuffix(got[n-3], `down 1`) || !strings.HasSuffix(got[n-2], `down 2`) {
+		t.Errorf("after the reconnect got %q", got)
+	}
+}
+
+func TestSyslogDropped(t *testing.T) {
+	ln, err := net.Listen(`tcp`, `127.0.0.1:0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	w := newSyslog(t, `tcp`, addr, WithSyslogBuffer(2), WithSyslogRetry(time.Hour))
+	for i := 0; i < 5; i++ {
+		_, _ = w.Write([]byte(`x`))
+	}
+	if n := w.Dropped(); n != 3 {
+		t.Errorf("Dropped() = %d, want 3", n)
+	}
+	if err = w.Close(); err == nil {
+		t.Error("Close() sent to a missing server")
+	}
+	if _, err = w.Write([]byte(`x`)); err != ErrSyslogClosed {
+		t.Errorf("Write after Close = %v, want ErrSyslogClosed", err)
+	}
+}
+
+func TestSyslogUnixgram(t *testing.T) {
+	if runtime.GOOS == `windows` {
+		t.Skip(`no unix datagram sockets`)
+	}
+	name := filepath.Join(t.TempDir(), `log.sock`)
+	pc, err := net.ListenPacket(`unixgram`, name)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer pc.Close()
+	w := newSyslog(t, `unixgram`, name, WithSyslogFormat(RFC3164))
+	_, _ = w.WriteLevel(LevelDebug, []byte("local\n"))
+	got := readPacket(t, pc)
+	if m := rfc3164Re.FindStringSubmatch(got); m == nil || m[1] != `15` || strings.Contains(got, ` host `) {
+		t.Errorf("not RFC 3164 without the host: %q", got)
+	}
+}
+
+func TestSyslogBadNetwork(t *testing.T) {
+	if _, err := NewSyslogWriter(`bogus`, `x`); err == nil {
+		t.Error("NewSyslogWriter accepted an unknown network")
+	}
+}
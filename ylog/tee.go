@@ -0,0 +1,374 @@
+package ylog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/azeroth-sha/y/ybuff"
+)
+
+// NewTee returns a Logger writing each line to all logs, each one filters
+// the lines by its own level and encodes them with its own encoder, e.g.
+//
+//	NewTee(
+//		NewLogger(WithLevel(LevelError), WithWriter(stderr)),
+//		NewLogger(WithLevel(LevelDebug), WithWriter(file), WithEncoder(JSONEncoder{}), WithAsync(1024, true)),
+//	)
+//
+// A failing log does not stop the others, its panic is reported to os.Stderr
+// and Entry counts the failed writes, see Entry.Failed. A stalled log does not
+// block the caller nor the others either: the caller encodes the lines of each
+// Entry and a queue of 1024 lines writes them in background, the lines of a
+// full queue are dropped and counted by Entry.Dropped. Other loggers are called
+// in turn by the caller, use WithAsync with drop on the ones whose writer may
+// stall. Panic, Fatal, Sync and Close wait up to 5s for the queues.
+func NewTee(logs ...Logger) Logger {
+	t := &tee{logs: make([]Logger, 0, len(logs))}
+	for _, l := range logs {
+		if l == nil {
+			continue
+		}
+		// skip tee.write, tee.output and the tee method
+		l = AddCallerSkip(l, 3)
+		var q *teeQueue
+		if _, ok := l.(*Entry); ok {
+			q = new(teeQueue)
+		}
+		t.logs = append(t.logs, l)
+		t.queues = append(t.queues, q)
+	}
+	return t
+}
+
+/*
+  Package private
+*/
+
+const (
+	teeQueueSize = 1024            // max queued lines of a log
+	teeWait      = 5 * time.Second // max wait of Panic, Fatal and Sync for the queues
+)
+
+var errTeeStalled = errors.New("ylog: tee log stalled")
+
+type tee struct {
+	logs   []Logger
+	queues []*teeQueue // queue of each Entry of logs, shared with the children
+}
+
+// teeQueue writes the lines of one Entry of a tee in background, its
+// goroutine runs while lines are queued.
+type teeQueue struct {
+	mu      sync.Mutex
+	lines   []teeLine
+	pending int // queued and being written lines
+	running bool
+}
+
+// teeLine is a line to write, or a call of fn answered on done.
+type teeLine struct {
+	level Level
+	buf   *bytes.Buffer
+	sink  *sink
+	fn    func() error
+	done  chan error
+}
+
+func (t *tee) Debug(args ...any) {
+	if t.Enabled(LevelDebug) {
+		t.output(LevelDebug, fmt.Sprint(args...), nil)
+	}
+}
+
+func (t *tee) Debugf(format string, args ...any) {
+	if t.Enabled(LevelDebug) {
+		t.output(LevelDebug, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (t *tee) Debugw(msg string, kv ...any) {
+	if t.Enabled(LevelDebug) {
+		t.output(LevelDebug, msg, kv)
+	}
+}
+
+func (t *tee) Info(args ...any) {
+	if t.Enabled(LevelInfo) {
+		t.output(LevelInfo, fmt.Sprint(args...), nil)
+	}
+}
+
+func (t *tee) Infof(format string, args ...any) {
+	if t.Enabled(LevelInfo) {
+		t.output(LevelInfo, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (t *tee) Infow(msg string, kv ...any) {
+	if t.Enabled(LevelInfo) {
+		t.output(LevelInfo, msg, kv)
+	}
+}
+
+func (t *tee) Warn(args ...any) {
+	if t.Enabled(LevelWarn) {
+		t.output(LevelWarn, fmt.Sprint(args...), nil)
+	}
+}
+
+func (t *tee) Warnf(format string, args ...any) {
+	if t.Enabled(LevelWarn) {
+		t.output(LevelWarn, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (t *tee) Warnw(msg string, kv ...any) {
+	if t.Enabled(LevelWarn) {
+		t.output(LevelWarn, msg, kv)
+	}
+}
+
+func (t *tee) Error(args ...any) {
+	if t.Enabled(LevelError) {
+		t.output(LevelError, fmt.Sprint(args...), nil)
+	}
+}
+
+func (t *tee) Errorf(format string, args ...any) {
+	if t.Enabled(LevelError) {
+		t.output(LevelError, fmt.Sprintf(format, args...), nil)
+	}
+}
+
+func (t *tee) Errorw(msg string, kv ...any) {
+	if t.Enabled(LevelError) {
+		t.output(LevelError, msg, kv)
+	}
+}
+
+func (t *tee) Panic(args ...any) {
+	msg := fmt.Sprint(args...)
+	if t.Enabled(LevelPanic) {
+		t.output(LevelPanic, msg, nil)
+		_ = t.flush(nil)
+	}
+	panic(msg)
+}
+
+func (t *tee) Panicf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if t.Enabled(LevelPanic) {
+		t.output(LevelPanic, msg, nil)
+		_ = t.flush(nil)
+	}
+	panic(msg)
+}
+
+func (t *tee) Panicw(msg string, kv ...any) {
+	if t.Enabled(LevelPanic) {
+		t.output(LevelPanic, msg, kv)
+		_ = t.flush(nil)
+	}
+	panic(msg)
+}
+
+func (t *tee) Fatal(args ...any) {
+	if t.Enabled(LevelFatal) {
+		t.output(LevelFatal, fmt.Sprint(args...), nil)
+	}
+	t.exit()
+}
+
+func (t *tee) Fatalf(format string, args ...any) {
+	if t.Enabled(LevelFatal) {
+		t.output(LevelFatal, fmt.Sprintf(format, args...), nil)
+	}
+	t.exit()
+}
+
+func (t *tee) Fatalw(msg string, kv ...any) {
+	if t.Enabled(LevelFatal) {
+		t.output(LevelFatal, msg, kv)
+	}
+	t.exit()
+}
+
+func (t *tee) Log(l Level, msg string, kv ...any) {
+	if t.Enabled(l) {
+		t.output(l, msg, kv)
+	}
+}
+
+func (t *tee) With(kv ...any) Logger {
+	c := &tee{logs: make([]Logger, len(t.logs)), queues: t.queues}
+	for i, l := range t.logs {
+		c.logs[i] = l.With(kv...)
+	}
+	return c
+}
+
+func (t *tee) Named(name string) Logger {
+	c := &tee{logs: make([]Logger, len(t.logs)), queues: t.queues}
+	for i, l := range t.logs {
+		c.logs[i] = Named(l, name)
+	}
+	return c
+}
+
+func (t *tee) AddCallerSkip(n int) Logger {
+	c := &tee{logs: make([]Logger, len(t.logs)), queues: t.queues}
+	for i, l := range t.logs {
+		c.logs[i] = AddCallerSkip(l, n)
+	}
+	return c
+}
+
+// Enabled returns whether any log writes lines of level l.
+func (t *tee) Enabled(l Level) bool {
+	for _, log := range t.logs {
+		if enabled(log, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sync writes the queued lines and syncs the logs.
+func (t *tee) Sync() error {
+	return t.flush(func(l Logger) func() error {
+		return l.Sync
+	})
+}
+
+// Close writes the queued lines and closes the logs implementing io.Closer.
+func (t *tee) Close() error {
+	return t.flush(func(l Logger) func() error {
+		if c, ok := l.(io.Closer); ok {
+			return c.Close
+		}
+		return nil
+	})
+}
+
+func (t *tee) output(l Level, msg string, kv []any) {
+	for i, log := range t.logs {
+		if enabled(log, l) {
+			t.write(i, log, l, msg, kv)
+		}
+	}
+}
+
+// write logs to one log, recovering its panic. An Entry encodes the line
+// here and its queue writes it.
+func (t *tee) write(i int, log Logger, l Level, msg string, kv []any) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ylog: tee log %d panic: %v\n", i, rec)
+		}
+	}()
+	if e, ok := log.(*Entry); ok && t.queues[i] != nil {
+		t.queues[i].put(teeLine{level: l, buf: e.encode(0, l, msg, kv), sink: e.sink})
+		return
+	}
+	log.Log(l, msg, kv...)
+}
+
+// flush waits up to teeWait for the queued lines to be written, then calls
+// fn(log) of each log, in background for the queued ones. fn may be nil.
+func (t *tee) flush(fn func(l Logger) func() error) error {
+	var errs []error
+	var waits []chan error
+	for i, l := range t.logs {
+		var f func() error
+		if fn != nil {
+			f = fn(l)
+		}
+		if t.queues[i] == nil {
+			if f != nil {
+				errs = append(errs, f())
+			}
+			continue
+		}
+		done := make(chan error, 1)
+		t.queues[i].put(teeLine{fn: f, done: done})
+		waits = append(waits, done)
+	}
+	timer := time.NewTimer(teeWait)
+	defer timer.Stop()
+	for _, done := range waits {
+		select {
+		case err := <-done:
+			errs = append(errs, err)
+		case <-timer.C:
+			return errors.Join(append(errs, errTeeStalled)...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *tee) exit() {
+	_ = t.Sync()
+	os.Exit(1)
+}
+
+// put queues the line, a full queue drops it.
+func (q *teeQueue) put(line teeLine) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if line.buf != nil {
+		if q.pending >= teeQueueSize {
+			line.sink.dropped.Add(1)
+			ybuff.Put(line.buf)
+			return
+		}
+		q.pending++
+	}
+	q.lines = append(q.lines, line)
+	if !q.running {
+		q.running = true
+		go q.drain()
+	}
+}
+
+// drain writes the queued lines until the queue is empty.
+func (q *teeQueue) drain() {
+	for {
+		q.mu.Lock()
+		lines := q.lines
+		q.lines = nil
+		if len(lines) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		q.mu.Unlock()
+		for _, line := range lines {
+			if line.buf != nil {
+				line.sink.write(line.level, line.buf)
+				q.mu.Lock()
+				q.pending--
+				q.mu.Unlock()
+			} else {
+				line.done <- q.call(line.fn)
+			}
+		}
+	}
+}
+
+// call runs fn, recovering its panic.
+func (q *teeQueue) call(fn func() error) (err error) {
+	if fn == nil {
+		return nil
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("ylog: tee log panic: %v", rec)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,115 @@
+package ylog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stallWriter blocks the writes until release is closed.
+type stallWriter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	lines   int
+}
+
+func (w *stallWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines++
+	return len(p), nil
+}
+
+func (*stallWriter) Close() error { return nil }
+
+// syncBuffer is a lineBuffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (*syncBuffer) Close() error { return nil }
+
+func (b *syncBuffer) Lines() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Count(b.buf.String(), "\n")
+}
+
+func TestTeeStall(t *testing.T) {
+	stall := &stallWriter{release: make(chan struct{})}
+	good := new(syncBuffer)
+	bad := NewLogger(WithWriter(stall), WithTime(false))
+	tl := NewTee(bad, NewLogger(WithWriter(good), WithTime(false)))
+	const lines = teeQueueSize + 100
+	// waits for the good log every 256 lines so only the stalled one drops
+	waitGood := func(n int) {
+		for deadline := time.Now().Add(time.Second); good.Lines() < n; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("good log wrote %d lines, want %d", good.Lines(), n)
+			}
+		}
+	}
+	for i := 1; i <= lines; i++ {
+		start := time.Now()
+		tl.Infow(`x`, `i`, i)
+		if d := time.Since(start); d > 100*time.Millisecond {
+			t.Fatalf("logging took %v with a stalled log", d)
+		}
+		if i%256 == 0 {
+			waitGood(i)
+		}
+	}
+	waitGood(lines)
+	dropped := bad.(*Entry).Dropped()
+	if want := uint64(lines - teeQueueSize); dropped != want {
+		t.Errorf("Dropped() = %d, want %d", dropped, want)
+	}
+	close(stall.release)
+	if err := tl.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	stall.mu.Lock()
+	defer stall.mu.Unlock()
+	if want := lines - int(dropped); stall.lines != want {
+		t.Errorf("stalled log wrote %d lines after the release, want %d", stall.lines, want)
+	}
+}
+
+func TestTeePanic(t *testing.T) {
+	buf := new(syncBuffer)
+	ok := NewLogger(WithWriter(buf), WithTime(false))
+	panicky := NewLogger(WithWriter(buf), WithTime(false), WithEncoder(panicEncoder{}))
+	tl := NewTee(panicky, ok)
+	tl.Info(`x`)
+	if err := tl.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if n := buf.Lines(); n != 1 {
+		t.Errorf("wrote %d lines, want 1 of the log not panicking", n)
+	}
+	func() {
+		defer func() {
+			if rec := recover(); rec != `boom` {
+				t.Errorf("recover() = %v, want boom", rec)
+			}
+		}()
+		tl.Panic(`boom`)
+	}()
+	if n := buf.Lines(); n != 2 {
+		t.Errorf("wrote %d lines, want the Panic line written before the panic", n)
+	}
+}
+
+type panicEncoder struct{}
+
+func (panicEncoder) Encode(*bytes.Buffer, *Record) { panic(`encoder`) }
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/azeroth-sha/y/yguid"
 	"github.com/azeroth-sha/y/ylog"
 )
 
@@ -14,9 +15,11 @@ var (
 )
 
 type Event interface {
+	ID() (id string)
 	Name() (name string)
 	Occur() (occur time.Time)
 	Request() (v any)
+	// Logger returns the logger given to Append with the task name and ID attached.
 	Logger() (log ylog.Logger)
 	Release(v any, err ...error) error
 }
@@ -27,6 +30,7 @@ type Reply interface {
 
 type task struct {
 	mu       *sync.Mutex
+	id       string
 	name     string
 	occur    time.Time
 	log      ylog.Logger
@@ -43,6 +47,10 @@ func (t *task) Wait() (any, error) {
 	return nil, ErrDone
 }
 
+func (t *task) ID() (id string) {
+	return t.id
+}
+
 func (t *task) Name() (name string) {
 	return t.name
 }
@@ -74,11 +82,13 @@ func (t *task) Release(v any, err ...error) error {
 
 // newTask returns a new task
 func newTask(name string, req any, log ylog.Logger) *task {
+	id := yguid.String()
 	t := &task{
 		mu:       new(sync.Mutex),
+		id:       id,
 		name:     name,
 		occur:    time.Now(),
-		log:      log,
+		log:      ylog.Named(log, `ystudio`).With(`task`, name, `task_id`, id),
 		request:  req,
 		response: make(chan any, 1),
 		err:      nil,